@@ -0,0 +1,183 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"frigate-custom-reviews/internal/config"
+	"frigate-custom-reviews/internal/frigate"
+	"frigate-custom-reviews/internal/logger"
+	"frigate-custom-reviews/internal/models"
+	"frigate-custom-reviews/internal/mqtt"
+	"frigate-custom-reviews/internal/replay"
+	"frigate-custom-reviews/internal/sink"
+)
+
+// backfillCommand computes the reviews for a past time range from the
+// Frigate events API and writes the review messages as JSON lines.
+func backfillCommand(args []string) {
+	fs := flag.NewFlagSet("backfill", flag.ExitOnError)
+	configPath := fs.String("config", "config.yaml", "Path to configuration file")
+	fromFlag := fs.String("from", "", "Start of the range: RFC 3339, \"2006-01-02 15:04\" (local time) or a duration ago such as 12h")
+	toFlag := fs.String("to", "", "End of the range, same formats as -from, default now")
+	outPath := fs.String("out", "", "Write review messages to this file instead of stdout")
+	capturePath := fs.String("capture", "", "Also write the fetched events as a capture file for replay and diff")
+	publish := fs.Bool("publish", false, "Also deliver review messages to the configured outputs, including live MQTT")
+	fs.Parse(args)
+
+	if *fromFlag == "" {
+		logger.Fatal("Usage: frigate-custom-reviews backfill -config config.yaml -from 12h [-to 2h] [-out reviews.jsonl] [-capture events.jsonl] [-publish]")
+	}
+
+	now := time.Now()
+	from, err := parseTimeFlag(*fromFlag, now)
+	if err != nil {
+		logger.Fatalf("Invalid -from: %v", err)
+	}
+	to := now
+	if *toFlag != "" {
+		if to, err = parseTimeFlag(*toFlag, now); err != nil {
+			logger.Fatalf("Invalid -to: %v", err)
+		}
+	}
+	if !from.Before(to) {
+		logger.Fatalf("-from (%v) must be before -to (%v)", from, to)
+	}
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		logger.Fatalf("Error loading config: %v", err)
+	}
+	logger.SetLevel(cfg.Logging.Level)
+
+	frigateClients, err := newFrigateClients(cfg.Sources)
+	if err != nil {
+		logger.Fatalf("Failed to configure Frigate API: %v", err)
+	}
+
+	var records []replay.Record
+	events, inProgress := 0, 0
+	for i, src := range cfg.Sources {
+		fetched, err := frigateClients[i].GetEvents(frigate.EventQuery{After: float64(from.Unix()), Before: float64(to.Unix())})
+		if err != nil {
+			logger.Fatalf("Failed to fetch events%s: %v", sourceSuffix(src), err)
+		}
+		// Events still in progress have no end yet, the engine would
+		// ghost-close them with a made-up end time
+		var states []models.FrigateEventState
+		for _, state := range fetched {
+			if state.EndTime == 0 {
+				inProgress++
+				continue
+			}
+			state.Source = src.Name
+			states = append(states, state)
+		}
+		converted, err := replay.FromEvents(states, src.EventsTopic)
+		if err != nil {
+			logger.Fatalf("Failed to convert events: %v", err)
+		}
+		records = append(records, converted...)
+		events += len(states)
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Time < records[j].Time
+	})
+	logger.Infof("Fetched %d events between %v and %v", events, from.Format(time.RFC3339), to.Format(time.RFC3339))
+	if inProgress > 0 {
+		logger.Warnf("Skipped %d events still in progress", inProgress)
+	}
+	if len(records) == 0 {
+		return
+	}
+
+	if *capturePath != "" {
+		if err := writeCapture(*capturePath, records); err != nil {
+			logger.Fatalf("Failed to write capture: %v", err)
+		}
+	}
+
+	var out io.Writer = os.Stdout
+	if *outPath != "" {
+		f, err := os.Create(*outPath)
+		if err != nil {
+			logger.Fatalf("Failed to create %s: %v", *outPath, err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	counter := &messageCounter{counts: make(map[string]map[string]int)}
+	outputs := sink.Fanout{sink.NewWriter("backfill", out), counter}
+
+	if *publish {
+		// Past reviews must not replace the live service's retained state,
+		// and the connection must not take over the service's session or
+		// availability topic
+		cfg.MQTT.StateTopic = ""
+		cfg.MQTT.ClientID += "-backfill"
+		cfg.MQTT.AvailabilityTopic = ""
+		cfg.MQTT.PersistentSession = false
+
+		// Sync delivery, a fast backfill must not overflow sink buffers
+		mqttClient, err := mqtt.NewClient(cfg.MQTT)
+		if err != nil {
+			logger.Fatalf("Failed to configure MQTT: %v", err)
+		}
+		if !cfg.Outputs.MQTT.Disabled {
+			if err := mqttClient.Connect(); err != nil {
+				logger.Fatalf("Failed to connect to MQTT: %v", err)
+			}
+			defer mqttClient.Disconnect()
+		}
+
+		live, err := openOutputs(cfg, mqttClient)
+		if err != nil {
+			logger.Fatalf("Failed to configure outputs: %v", err)
+		}
+		for _, o := range live {
+			outputs = append(outputs, sink.Filtered(o.sink, o.options))
+		}
+	}
+	defer outputs.Close()
+
+	stats, ingest := replayRecords(cfg, records, outputs, replay.Options{})
+	logger.Infof("Backfilled %d events (%d skipped)", events, stats.Skipped)
+	logIngestStats(ingest)
+	counter.logSummary()
+}
+
+// parseTimeFlag accepts an RFC 3339 time, a local "2006-01-02 15:04" time,
+// or a duration before now
+func parseTimeFlag(value string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04", value, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time %q", value)
+}
+
+func writeCapture(path string, records []replay.Record) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := replay.NewWriter(f)
+	for _, rec := range records {
+		if err := w.Write(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
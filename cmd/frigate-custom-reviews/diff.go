@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"sync"
+
+	"frigate-custom-reviews/internal/config"
+	"frigate-custom-reviews/internal/logger"
+	"frigate-custom-reviews/internal/models"
+	"frigate-custom-reviews/internal/replay"
+	"frigate-custom-reviews/internal/whatif"
+)
+
+// diffCommand replays the same captured events through two configs and
+// reports how the resulting reviews differ.
+func diffCommand(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	pathA := fs.String("a", "", "Current configuration file")
+	pathB := fs.String("b", "", "Proposed configuration file")
+	inPath := fs.String("in", "", "Captured events file (JSONL)")
+	asJSON := fs.Bool("json", false, "Print the report as JSON")
+	fs.Parse(args)
+
+	if *pathA == "" || *pathB == "" || *inPath == "" {
+		logger.Fatal("Usage: frigate-custom-reviews diff -a current.yaml -b proposed.yaml -in events.jsonl [-json]")
+	}
+
+	cfgA, err := config.LoadConfig(*pathA)
+	if err != nil {
+		logger.Fatalf("Error loading config %s: %v", *pathA, err)
+	}
+	cfgB, err := config.LoadConfig(*pathB)
+	if err != nil {
+		logger.Fatalf("Error loading config %s: %v", *pathB, err)
+	}
+	// Engine logging from two runs would interleave, keep it to warnings
+	logger.SetLevel("warn")
+
+	records, err := replay.ReadFile(*inPath)
+	if err != nil {
+		logger.Fatalf("Failed to read %s: %v", *inPath, err)
+	}
+
+	var reviewsA, reviewsB []models.ReviewState
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		reviewsA = collectReviews(cfgA, records)
+	}()
+	go func() {
+		defer wg.Done()
+		reviewsB = collectReviews(cfgB, records)
+	}()
+	wg.Wait()
+
+	report := whatif.Compare(reviewsA, reviewsB)
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			logger.Fatalf("Failed to encode report: %v", err)
+		}
+		return
+	}
+	report.WriteText(os.Stdout)
+}
+
+func collectReviews(cfg *models.Config, records []replay.Record) []models.ReviewState {
+	collector := replay.NewCollector()
+	replayRecords(cfg, records, collector, replay.Options{})
+	return collector.Reviews()
+}
@@ -2,19 +2,43 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"os"
 	"os/signal"
+	"sort"
 	"syscall"
+	"time"
 
+	"frigate-custom-reviews/internal/audit"
+	"frigate-custom-reviews/internal/clock"
 	"frigate-custom-reviews/internal/config"
 	"frigate-custom-reviews/internal/engine"
 	"frigate-custom-reviews/internal/frigate"
 	"frigate-custom-reviews/internal/logger"
+	"frigate-custom-reviews/internal/models"
 	"frigate-custom-reviews/internal/mqtt"
+	"frigate-custom-reviews/internal/reconcile"
+	"frigate-custom-reviews/internal/sink"
 )
 
+// subcommands run offline tools instead of the live service
+var subcommands = map[string]func(args []string){
+	"replay":   replayCommand,
+	"diff":     diffCommand,
+	"record":   recordCommand,
+	"backfill": backfillCommand,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := subcommands[os.Args[1]]; ok {
+			cmd(os.Args[2:])
+			return
+		}
+	}
+
 	configPath := flag.String("config", "config.yaml", "Path to configuration file")
+	strict := flag.Bool("strict", false, "Exit if a profile references a camera, zone or label Frigate doesn't have")
 	flag.Parse()
 
 	// 1. Load Configuration
@@ -27,40 +51,98 @@ func main() {
 	logger.Infof("Loaded config from %s", *configPath)
 
 	// 2. Initialize Clients
-	mqttClient := mqtt.NewClient(cfg.MQTT)
-	frigateClient := frigate.NewClient(cfg.Frigate)
+	mqttClient, err := mqtt.NewClient(cfg.MQTT)
+	if err != nil {
+		logger.Fatalf("Failed to configure MQTT: %v", err)
+	}
+	frigateClients, err := newFrigateClients(cfg.Sources)
+	if err != nil {
+		logger.Fatalf("Failed to configure Frigate API: %v", err)
+	}
+	validateProfiles(cfg.Profiles, cfg.Sources, frigateClients, *strict)
+
+	outputs, err := buildOutputs(cfg, mqttClient)
+	if err != nil {
+		logger.Fatalf("Failed to configure outputs: %v", err)
+	}
 
 	// 3. Initialize Engine
-	eng := engine.NewEngine(cfg.Profiles, mqttClient, cfg.MQTT.ReviewsPublishTopic, engine.WithGhostTimeout(cfg.GhostTimeout), engine.WithPublishUpdates(cfg.PublishUpdates))
+	engineOpts := []engine.EngineOption{
+		engine.WithGhostTimeout(cfg.GhostTimeout),
+		engine.WithPublishUpdates(cfg.PublishUpdates),
+	}
 
-	// 4. Recover State from Frigate API
-	logger.Info("Querying Frigate API for active events...")
-	activeEvents, err := frigateClient.GetActiveEvents()
-	if err != nil {
-		logger.Warnf("Failed to query Frigate API: %v", err)
-	} else {
-		logger.Infof("Found %d active events from API", len(activeEvents))
-		ingest := eng.IngestChannel()
-		for _, evt := range activeEvents {
-			ingest <- evt
+	if cfg.Audit.Path != "" {
+		auditLog, err := audit.NewLog(cfg.Audit)
+		if err != nil {
+			logger.Fatalf("Failed to open audit log: %v", err)
 		}
+		defer auditLog.Close()
+		engineOpts = append(engineOpts, engine.WithAuditor(auditLog))
+		logger.Infof("Writing audit log to %s", cfg.Audit.Path)
 	}
 
-	// 5. Connect to MQTT
+	eng := engine.NewEngine(cfg.Profiles, outputs, engineOpts...)
+
+	// 4. Connect to MQTT
 	if err := mqttClient.Connect(); err != nil {
 		logger.Fatalf("Failed to connect to MQTT: %v", err)
 	}
 	defer mqttClient.Disconnect()
+	// Deferred after Disconnect so queued messages are flushed while still connected
+	defer outputs.Close()
 
-	// 6. Subscribe to Frigate Events
+	// 5. Start Engine (Blocking or Non-blocking? Engine.Run is blocking)
+	// We run it in a goroutine so we can handle signals. It must be running
+	// before recovery, which is processed by the Run loop.
+	go eng.Run()
+
+	// 6. Recover State from Frigate API: in-progress events, plus events that
+	// ended within the largest gap so reviews waiting out their gap survive a restart
+	logger.Info("Querying Frigate API for active and recently ended events...")
+	var startupEvents []models.FrigateEvent
+	for i, src := range cfg.Sources {
+		events, err := reconcile.StartupEvents(src.Name, frigateClients[i], time.Now(), maxGap(cfg.Profiles))
+		if err != nil {
+			logger.Warnf("Failed to query Frigate API%s: %v", sourceSuffix(src), err)
+			continue
+		}
+		startupEvents = append(startupEvents, events...)
+	}
+	// Sources are recovered together so the engine sees events in start order
+	sort.SliceStable(startupEvents, func(i, j int) bool {
+		return startupEvents[i].After.StartTime < startupEvents[j].After.StartTime
+	})
+	logger.Infof("Recovering %d events from API", len(startupEvents))
+	eng.Recover(startupEvents)
+
+	// 7. Subscribe to Frigate Events
 	// We pass the engine's ingest channel directly to the MQTT subscriber
-	if err := mqttClient.Subscribe(eng.IngestChannel()); err != nil {
+	if err := mqttClient.Subscribe(eng.IngestChannel(), cfg.Sources); err != nil {
 		logger.Fatalf("Failed to subscribe to topic: %v", err)
 	}
 
-	// 7. Start Engine (Blocking or Non-blocking? Engine.Run is blocking)
-	// We run it in a goroutine so we can handle signals
-	go eng.Run()
+	done := make(chan struct{})
+	defer close(done)
+	for i, src := range cfg.Sources {
+		reconciler := reconcile.New(src.Name, frigateClients[i], eng, eng.IngestChannel(), clock.Real())
+
+		// Events may have ended while the connection was down, resync with the API
+		mqttClient.OnReconnect(func() {
+			result, err := reconciler.Run()
+			if err != nil {
+				logger.Warnf("Failed to resync with Frigate%s after reconnect: %v", sourceSuffix(src), err)
+				return
+			}
+			logger.Infof("Resynced with Frigate%s after reconnect: %d events ended, %d started",
+				sourceSuffix(src), result.Ended, result.Started)
+		})
+
+		// Catch missed 'end' messages long before the ghost timeout
+		if cfg.ReconcileInterval > 0 {
+			go reconciler.RunEvery(time.Duration(cfg.ReconcileInterval)*time.Second, done)
+		}
+	}
 
 	// 8. Wait for Signal
 	sigChan := make(chan os.Signal, 1)
@@ -68,4 +150,139 @@ func main() {
 
 	sig := <-sigChan
 	logger.Infof("Received signal %v, shutting down...", sig)
+	logIngestStats(eng.Stats())
+	queueStats := mqttClient.IngestStats()
+	logger.Infof("Ingest queue: %d dropped, %d coalesced", queueStats.Dropped, queueStats.Coalesced)
+}
+
+// output is a configured sink with its filters and buffer size
+type output struct {
+	sink    sink.Sink
+	options models.OutputOptions
+}
+
+// openOutputs opens every sink enabled in the config
+func openOutputs(cfg *models.Config, mqttClient *mqtt.Client) ([]output, error) {
+	var outputs []output
+
+	if !cfg.Outputs.MQTT.Disabled {
+		outputs = append(outputs, output{sink.NewMQTT(mqttClient, cfg.MQTT), cfg.Outputs.MQTT.OutputOptions})
+	}
+
+	for _, hookCfg := range cfg.Outputs.Webhooks {
+		hook, err := sink.NewWebhook(hookCfg)
+		if err != nil {
+			closeOutputs(outputs)
+			return nil, err
+		}
+		outputs = append(outputs, output{hook, hookCfg.OutputOptions})
+	}
+
+	for _, fileCfg := range cfg.Outputs.Files {
+		f, err := sink.NewFile(fileCfg)
+		if err != nil {
+			closeOutputs(outputs)
+			return nil, err
+		}
+		outputs = append(outputs, output{f, fileCfg.OutputOptions})
+	}
+
+	return outputs, nil
+}
+
+func closeOutputs(outputs []output) {
+	for _, out := range outputs {
+		out.sink.Close()
+	}
+}
+
+// buildOutputs registers every configured sink with a dispatcher
+func buildOutputs(cfg *models.Config, mqttClient *mqtt.Client) (*sink.Dispatcher, error) {
+	outputs, err := openOutputs(cfg, mqttClient)
+	if err != nil {
+		return nil, err
+	}
+
+	d := sink.NewDispatcher()
+	for _, out := range outputs {
+		d.Add(out.sink, out.options)
+	}
+
+	if d.Len() == 0 {
+		logger.Warn("No outputs enabled, reviews will not be published anywhere")
+	}
+
+	return d, nil
+}
+
+// newFrigateClients returns an API client per source, in the same order
+func newFrigateClients(sources []models.SourceConfig) ([]*frigate.Client, error) {
+	clients := make([]*frigate.Client, 0, len(sources))
+	for _, src := range sources {
+		client, err := frigate.NewClient(src.FrigateConfig)
+		if err != nil {
+			return nil, fmt.Errorf("source %q: %w", src.Name, err)
+		}
+		clients = append(clients, client)
+	}
+	return clients, nil
+}
+
+// sourceSuffix names a source in log messages, empty for the unnamed source
+func sourceSuffix(src models.SourceConfig) string {
+	if src.Name == "" {
+		return ""
+	}
+	return " source " + src.Name
+}
+
+// validateProfiles checks the profiles against the combined config of every
+// Frigate source, warning about settings that can never match, or exiting
+// in strict mode
+func validateProfiles(profiles []models.Profile, sources []models.SourceConfig, clients []*frigate.Client, strict bool) {
+	configs := make(map[string]*frigate.Config, len(sources))
+	for i, src := range sources {
+		cfg, err := clients[i].GetConfig()
+		if err != nil {
+			if strict {
+				logger.Fatalf("Failed to fetch Frigate config%s to validate profiles: %v", sourceSuffix(src), err)
+			}
+			logger.Warnf("Skipping profile validation, failed to fetch Frigate config%s: %v", sourceSuffix(src), err)
+			return
+		}
+		configs[src.Name] = cfg
+	}
+	frigateCfg := frigate.Merge(configs)
+
+	problems := frigateCfg.ValidateProfiles(profiles)
+	if len(problems) == 0 {
+		logger.Infof("Validated %d profiles against Frigate config", len(profiles))
+		return
+	}
+
+	for _, p := range problems {
+		logger.Warnf("Invalid %s", p)
+	}
+	logger.Info("Frigate config has:")
+	for _, line := range frigateCfg.Describe() {
+		logger.Infof("  %s", line)
+	}
+
+	if strict {
+		logger.Fatalf("Found %d profile problems, exiting (strict)", len(problems))
+	}
+}
+
+// maxGap returns the longest profile gap
+func maxGap(profiles []models.Profile) time.Duration {
+	longest := 0
+	for _, p := range profiles {
+		longest = max(longest, p.Gap)
+	}
+	return time.Duration(longest) * time.Second
+}
+
+func logIngestStats(stats engine.Stats) {
+	logger.Infof("Ingested events: %d accepted, %d unknown type, %d stale, %d duplicate",
+		stats.Accepted, stats.UnknownType, stats.Stale, stats.Duplicate)
 }
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"frigate-custom-reviews/internal/config"
+	"frigate-custom-reviews/internal/logger"
+	"frigate-custom-reviews/internal/models"
+	"frigate-custom-reviews/internal/mqtt"
+	"frigate-custom-reviews/internal/replay"
+	"frigate-custom-reviews/internal/rotate"
+)
+
+// recordCommand captures live Frigate events from MQTT into a rotating JSONL
+// file that the replay and diff subcommands can read.
+func recordCommand(args []string) {
+	fs := flag.NewFlagSet("record", flag.ExitOnError)
+	configPath := fs.String("config", "config.yaml", "Path to configuration file")
+	outPath := fs.String("out", "events.jsonl", "Capture file (JSONL)")
+	cameras := fs.String("cameras", "", "Comma separated cameras to record, bare or as source/camera, empty records all")
+	maxSizeMB := fs.Int("max-size-mb", 100, "Rotate the capture once it reaches this size, 0 disables rotation")
+	maxBackups := fs.Int("max-backups", 5, "Rotated capture files to keep")
+	fs.Parse(args)
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		logger.Fatalf("Error loading config: %v", err)
+	}
+	logger.SetLevel(cfg.Logging.Level)
+
+	var cameraFilter []string
+	if *cameras != "" {
+		cameraFilter = strings.Split(*cameras, ",")
+	}
+
+	out, err := rotate.NewWriter(*outPath, int64(*maxSizeMB)*1024*1024, *maxBackups)
+	if err != nil {
+		logger.Fatalf("Failed to open capture file: %v", err)
+	}
+	defer out.Close()
+	writer := replay.NewWriter(out)
+
+	// Run alongside the live service without taking over its session or availability topic
+	mqttCfg := cfg.MQTT
+	mqttCfg.ClientID += "-recorder"
+	mqttCfg.AvailabilityTopic = ""
+	mqttCfg.PersistentSession = false
+	mqttClient, err := mqtt.NewClient(mqttCfg)
+	if err != nil {
+		logger.Fatalf("Failed to configure MQTT: %v", err)
+	}
+
+	if err := mqttClient.Connect(); err != nil {
+		logger.Fatalf("Failed to connect to MQTT: %v", err)
+	}
+	defer mqttClient.Disconnect()
+
+	// Paho calls handlers from a single router goroutine, so writes are serialized
+	var recorded atomic.Int64
+	recorder := func(source string) func(topic string, payload []byte) {
+		return func(topic string, payload []byte) {
+			rec := replay.Record{
+				Time:    float64(time.Now().UnixNano()) / 1e9,
+				Topic:   topic,
+				Payload: payload,
+			}
+
+			camera := models.FrigateEventState{Source: source, Camera: payloadCamera(payload)}
+			if len(cameraFilter) > 0 && !slices.ContainsFunc(cameraFilter, func(c string) bool {
+				return c == camera.Camera || c == camera.QualifiedCamera()
+			}) {
+				return
+			}
+
+			if err := writer.Write(rec); err != nil {
+				logger.Errorf("Failed to record message: %v", err)
+				return
+			}
+			logger.Debugf("Recorded message %d from %s", recorded.Add(1), topic)
+		}
+	}
+
+	// The topic is recorded with each message, replay maps it back to its source
+	topics := make([]string, 0, len(cfg.Sources))
+	for _, src := range cfg.Sources {
+		for _, topic := range []string{src.EventsTopic, src.ReviewsTopic} {
+			if topic == "" {
+				continue
+			}
+			if err := mqttClient.SubscribeRaw(topic, recorder(src.Name)); err != nil {
+				logger.Fatalf("Failed to subscribe to topic: %v", err)
+			}
+			topics = append(topics, topic)
+		}
+	}
+
+	logger.Infof("Recording %s to %s", strings.Join(topics, ", "), *outPath)
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	sig := <-sigChan
+	logger.Infof("Received signal %v, recorded %d messages", sig, recorded.Load())
+}
+
+// payloadCamera extracts the camera of a Frigate message without decoding all of it
+func payloadCamera(payload []byte) string {
+	var msg struct {
+		After struct {
+			Camera string `json:"camera"`
+		} `json:"after"`
+	}
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		return ""
+	}
+	return msg.After.Camera
+}
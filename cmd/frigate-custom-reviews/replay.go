@@ -0,0 +1,128 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"time"
+
+	"frigate-custom-reviews/internal/clock"
+	"frigate-custom-reviews/internal/config"
+	"frigate-custom-reviews/internal/engine"
+	"frigate-custom-reviews/internal/logger"
+	"frigate-custom-reviews/internal/models"
+	"frigate-custom-reviews/internal/replay"
+	"frigate-custom-reviews/internal/sink"
+)
+
+// replayCommand runs a captured event stream through the engine on a virtual
+// clock and writes the resulting review messages as JSON lines.
+func replayCommand(args []string) {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	configPath := fs.String("config", "config.yaml", "Path to configuration file")
+	inPath := fs.String("in", "", "Captured events file (JSONL)")
+	outPath := fs.String("out", "", "Write review messages to this file instead of stdout")
+	speed := fs.Float64("speed", 0, "Replay speed multiplier (e.g. 60 = one hour per minute), 0 = as fast as possible")
+	fs.Parse(args)
+
+	if *inPath == "" && fs.NArg() > 0 {
+		*inPath = fs.Arg(0)
+	}
+	if *inPath == "" {
+		logger.Fatal("Usage: frigate-custom-reviews replay -config config.yaml -in events.jsonl [-out reviews.jsonl] [-speed N]")
+	}
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		logger.Fatalf("Error loading config: %v", err)
+	}
+	logger.SetLevel(cfg.Logging.Level)
+
+	records, err := replay.ReadFile(*inPath)
+	if err != nil {
+		logger.Fatalf("Failed to read %s: %v", *inPath, err)
+	}
+	if len(records) == 0 {
+		logger.Fatalf("No records in %s", *inPath)
+	}
+
+	var out io.Writer = os.Stdout
+	if *outPath != "" {
+		f, err := os.Create(*outPath)
+		if err != nil {
+			logger.Fatalf("Failed to create %s: %v", *outPath, err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	counter := &messageCounter{counts: make(map[string]map[string]int)}
+	outputs := sink.Fanout{sink.NewWriter("replay", out), counter}
+	defer outputs.Close()
+
+	started := time.Now()
+	stats, ingest := replayRecords(cfg, records, outputs, replay.Options{Speed: *speed})
+
+	logger.Infof("Replayed %d records (%d events, %d skipped) spanning %v in %v",
+		stats.Records, stats.Events, stats.Skipped,
+		records[len(records)-1].Timestamp().Sub(records[0].Timestamp()).Round(time.Second),
+		time.Since(started).Round(time.Millisecond))
+	logIngestStats(ingest)
+	counter.logSummary()
+}
+
+// replayRecords runs records through a fresh engine for cfg on its own virtual clock
+func replayRecords(cfg *models.Config, records []replay.Record, publisher engine.Publisher, opts replay.Options) (replay.Stats, engine.Stats) {
+	start := time.Now()
+	if len(records) > 0 {
+		start = records[0].Timestamp()
+	}
+
+	if opts.Sources == nil {
+		opts.Sources = make(map[string]string, len(cfg.Sources))
+		opts.ReviewTopics = make(map[string]bool)
+		for _, src := range cfg.Sources {
+			opts.Sources[src.EventsTopic] = src.Name
+			if src.ReviewsTopic != "" {
+				opts.Sources[src.ReviewsTopic] = src.Name
+				opts.ReviewTopics[src.ReviewsTopic] = true
+			}
+		}
+	}
+
+	clk := clock.NewVirtual(start)
+	eng := engine.NewEngine(cfg.Profiles, publisher,
+		engine.WithClock(clk),
+		engine.WithGhostTimeout(cfg.GhostTimeout),
+		engine.WithPublishUpdates(cfg.PublishUpdates),
+	)
+
+	stats := replay.Run(eng, clk, records, opts)
+	return stats, eng.Stats()
+}
+
+// messageCounter tallies emitted messages per profile and type for the summary
+type messageCounter struct {
+	counts map[string]map[string]int
+}
+
+func (c *messageCounter) Name() string { return "counter" }
+func (c *messageCounter) Close() error { return nil }
+
+func (c *messageCounter) logSummary() {
+	for profile, counts := range c.counts {
+		logger.Infof("Profile %s: %d new, %d updates, %d ended", profile,
+			counts[models.MessageNew], counts[models.MessageUpdate], counts[models.MessageEnd])
+	}
+}
+
+func (c *messageCounter) Send(msg models.MessagePayload) error {
+	if msg.After == nil {
+		return nil
+	}
+	if c.counts[msg.After.ProfileName] == nil {
+		c.counts[msg.After.ProfileName] = make(map[string]int)
+	}
+	c.counts[msg.After.ProfileName][msg.Type]++
+	return nil
+}
@@ -0,0 +1,69 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"frigate-custom-reviews/internal/logger"
+	"frigate-custom-reviews/internal/models"
+	"frigate-custom-reviews/internal/rotate"
+)
+
+// Record kinds
+const (
+	KindEvent      = "event"      // A FrigateEvent was ingested
+	KindMatch      = "match"      // An event matched a profile
+	KindReject     = "reject"     // An event was rejected by a profile
+	KindTransition = "transition" // A review or event changed state
+	KindIgnored    = "ignored"    // An event was dropped before matching (unknown type, stale, duplicate)
+)
+
+// Record is a single line of the audit log
+type Record struct {
+	Time      time.Time            `json:"time"`
+	Kind      string               `json:"kind"`
+	EventID   string               `json:"event_id,omitempty"`
+	Profile   string               `json:"profile,omitempty"`
+	ReviewID  string               `json:"review_id,omitempty"`
+	Criterion string               `json:"criterion,omitempty"` // Reject: the failed profile criterion, see the engine's criterion* constants
+	From      string               `json:"from,omitempty"`      // Transition: previous state
+	To        string               `json:"to,omitempty"`        // Transition: new state
+	Reason    string               `json:"reason,omitempty"`    // Transition or ignored: why
+	Event     *models.FrigateEvent `json:"event,omitempty"`     // Event: the ingested payload
+}
+
+// Log writes audit records as newline-delimited JSON to a size-rotated file
+type Log struct {
+	writer *rotate.Writer
+}
+
+func NewLog(cfg models.AuditConfig) (*Log, error) {
+	w, err := rotate.NewWriter(cfg.Path, int64(cfg.MaxSizeMB)*1024*1024, cfg.MaxBackups)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &Log{writer: w}, nil
+}
+
+// Record appends rec, stamping the time if unset. Failures are logged rather
+// than returned since auditing must never interrupt event processing.
+func (l *Log) Record(rec Record) {
+	if rec.Time.IsZero() {
+		rec.Time = time.Now()
+	}
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		logger.Errorf("Failed to marshal audit record: %v", err)
+		return
+	}
+
+	if _, err := l.writer.Write(append(data, '\n')); err != nil {
+		logger.Errorf("Failed to write audit record: %v", err)
+	}
+}
+
+func (l *Log) Close() error {
+	return l.writer.Close()
+}
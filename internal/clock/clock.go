@@ -0,0 +1,131 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock abstracts the current time and tickers so the engine can run against
+// recorded timestamps or be stepped precisely in tests.
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker mirrors time.Ticker behind an interface
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+type realTicker struct {
+	t *time.Ticker
+}
+
+func (r realTicker) C() <-chan time.Time { return r.t.C }
+func (r realTicker) Stop()               { r.t.Stop() }
+
+// Real returns a Clock backed by the system clock
+func Real() Clock {
+	return realClock{}
+}
+
+// Virtual is a Clock that only moves when told to. Its tickers fire as the
+// clock is moved past their deadlines; like time.Ticker, ticks that aren't
+// received in time are dropped.
+type Virtual struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*virtualTicker
+}
+
+func NewVirtual(start time.Time) *Virtual {
+	return &Virtual{now: start}
+}
+
+func (v *Virtual) Now() time.Time {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.now
+}
+
+func (v *Virtual) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	t := &virtualTicker{
+		clock:  v,
+		period: d,
+		next:   v.now.Add(d),
+		c:      make(chan time.Time, 1),
+	}
+	v.tickers = append(v.tickers, t)
+	return t
+}
+
+// Set moves the clock to t. Moving backwards is ignored.
+func (v *Virtual) Set(t time.Time) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if t.After(v.now) {
+		v.now = t
+		v.fire()
+	}
+}
+
+func (v *Virtual) Advance(d time.Duration) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.now = v.now.Add(d)
+	v.fire()
+}
+
+// fire delivers every tick that is now due. Callers must hold v.mu.
+func (v *Virtual) fire() {
+	for _, t := range v.tickers {
+		for !t.next.After(v.now) {
+			select {
+			case t.c <- t.next:
+			default:
+			}
+			t.next = t.next.Add(t.period)
+		}
+	}
+}
+
+type virtualTicker struct {
+	clock  *Virtual
+	period time.Duration
+	next   time.Time
+	c      chan time.Time
+}
+
+func (t *virtualTicker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *virtualTicker) Stop() {
+	v := t.clock
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for i, other := range v.tickers {
+		if other == t {
+			v.tickers = append(v.tickers[:i], v.tickers[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVirtual_TickerFiresOnAdvance(t *testing.T) {
+	start := time.Unix(1000, 0)
+	v := NewVirtual(start)
+	ticker := v.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	v.Advance(500 * time.Millisecond)
+	select {
+	case <-ticker.C():
+		t.Fatal("Ticker fired before its interval")
+	default:
+	}
+
+	v.Advance(500 * time.Millisecond)
+	select {
+	case tick := <-ticker.C():
+		if !tick.Equal(start.Add(time.Second)) {
+			t.Errorf("Tick at %v, want %v", tick, start.Add(time.Second))
+		}
+	default:
+		t.Fatal("Ticker did not fire after its interval")
+	}
+}
+
+func TestVirtual_DropsMissedTicks(t *testing.T) {
+	v := NewVirtual(time.Unix(1000, 0))
+	ticker := v.NewTicker(time.Second)
+
+	// Like time.Ticker, only one tick is buffered while nobody is receiving
+	v.Advance(5 * time.Second)
+	<-ticker.C()
+	select {
+	case <-ticker.C():
+		t.Fatal("Expected missed ticks to be dropped")
+	default:
+	}
+
+	// Deadlines keep their original phase
+	v.Advance(time.Second)
+	if tick := <-ticker.C(); tick.Unix() != 1006 {
+		t.Errorf("Tick at %d, want 1006", tick.Unix())
+	}
+
+	ticker.Stop()
+	v.Advance(time.Second)
+	select {
+	case <-ticker.C():
+		t.Fatal("Stopped ticker fired")
+	default:
+	}
+}
+
+func TestVirtual_SetIgnoresPast(t *testing.T) {
+	v := NewVirtual(time.Unix(1000, 0))
+	v.Set(time.Unix(900, 0))
+	if got := v.Now().Unix(); got != 1000 {
+		t.Errorf("Now = %d, want 1000", got)
+	}
+}
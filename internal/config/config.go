@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"frigate-custom-reviews/internal/models"
 
@@ -28,6 +29,18 @@ func LoadConfig(path string) (*models.Config, error) {
 	if cfg.MQTT.ReviewsPublishTopic == "" {
 		cfg.MQTT.ReviewsPublishTopic = "frigate_custom_reviews/reviews"
 	}
+	if cfg.MQTT.StateTopic == "" {
+		cfg.MQTT.StateTopic = "frigate_custom_reviews/{profile}/state"
+	}
+	if cfg.MQTT.AvailabilityTopic == "" {
+		cfg.MQTT.AvailabilityTopic = "frigate_custom_reviews/available"
+	}
+	if cfg.MQTT.IngestQueueSize == 0 {
+		cfg.MQTT.IngestQueueSize = 1000
+	}
+	if cfg.MQTT.OverflowPolicy == "" {
+		cfg.MQTT.OverflowPolicy = "drop_oldest"
+	}
 	if cfg.MQTT.ClientID == "" {
 		cfg.MQTT.ClientID = "frigate-custom-reviews"
 	}
@@ -35,6 +48,23 @@ func LoadConfig(path string) (*models.Config, error) {
 	if cfg.GhostTimeout == 0 {
 		cfg.GhostTimeout = 300
 	}
+	if cfg.ReconcileInterval == 0 {
+		cfg.ReconcileInterval = 60
+	}
+
+	if cfg.Audit.MaxSizeMB == 0 {
+		cfg.Audit.MaxSizeMB = 10
+	}
+	if cfg.Audit.MaxBackups == 0 {
+		cfg.Audit.MaxBackups = 3
+	}
+
+	if err := resolveSources(&cfg); err != nil {
+		return nil, err
+	}
+	if err := resolveProfiles(&cfg); err != nil {
+		return nil, err
+	}
 
 	if cfg.Logging.Level == "" {
 		cfg.Logging.Level = "info"
@@ -45,3 +75,80 @@ func LoadConfig(path string) (*models.Config, error) {
 
 	return &cfg, nil
 }
+
+// resolveSources turns the single frigate/frigate_events_topic setup into
+// an unnamed source, or checks that configured sources can be told apart
+func resolveSources(cfg *models.Config) error {
+	if len(cfg.Sources) == 0 {
+		cfg.Sources = []models.SourceConfig{{
+			EventsTopic:   cfg.MQTT.FrigateEventsTopic,
+			ReviewsTopic:  cfg.MQTT.FrigateReviewsTopic,
+			FrigateConfig: cfg.Frigate,
+		}}
+		return nil
+	}
+
+	names := make(map[string]bool)
+	topics := make(map[string]bool)
+	for _, src := range cfg.Sources {
+		if src.Name == "" || strings.Contains(src.Name, "/") {
+			return fmt.Errorf("source name %q must be set and must not contain '/'", src.Name)
+		}
+		if src.EventsTopic == "" {
+			return fmt.Errorf("source %q has no events_topic", src.Name)
+		}
+		if names[src.Name] || topics[src.EventsTopic] || topics[src.ReviewsTopic] {
+			return fmt.Errorf("source %q repeats a name or topic", src.Name)
+		}
+		names[src.Name] = true
+		topics[src.EventsTopic] = true
+		if src.ReviewsTopic != "" {
+			topics[src.ReviewsTopic] = true
+		}
+	}
+	return nil
+}
+
+// resolveProfiles defaults each profile's input, to reviews when it filters
+// on severity, its point duration and zone mode. It checks that review
+// input has a topic to come from.
+func resolveProfiles(cfg *models.Config) error {
+	reviewsTopic := false
+	for _, src := range cfg.Sources {
+		reviewsTopic = reviewsTopic || src.ReviewsTopic != ""
+	}
+
+	for i := range cfg.Profiles {
+		p := &cfg.Profiles[i]
+		if p.PointDuration == 0 {
+			p.PointDuration = 5
+		}
+		switch p.ZoneMode {
+		case "":
+			p.ZoneMode = models.ZoneModeEntered
+		case models.ZoneModeEntered, models.ZoneModeCurrent, models.ZoneModeSinceReview:
+		default:
+			return fmt.Errorf("profile %q: unknown zone_mode %q", p.Name, p.ZoneMode)
+		}
+		if p.Input == "" {
+			p.Input = models.InputEvents
+			if len(p.Severities) > 0 {
+				p.Input = models.InputReviews
+			}
+		}
+
+		switch p.Input {
+		case models.InputEvents:
+			if len(p.Severities) > 0 {
+				return fmt.Errorf("profile %q: severities require input %q", p.Name, models.InputReviews)
+			}
+		case models.InputReviews:
+			if !reviewsTopic {
+				return fmt.Errorf("profile %q: input %q requires frigate_reviews_topic (or a source reviews_topic)", p.Name, models.InputReviews)
+			}
+		default:
+			return fmt.Errorf("profile %q: unknown input %q", p.Name, p.Input)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"slices"
+
+	"frigate-custom-reviews/internal/models"
+)
+
+// diffReviewStates lists what changed between two states of a review, in a
+// fixed order. A nil before is a review that was not published yet. Times
+// alone are not reported, so an empty result means the update is a no-op.
+func diffReviewStates(before, after *models.ReviewState) []string {
+	var changes []string
+	if after == nil {
+		return changes
+	}
+
+	var prev models.ReviewState
+	if before != nil {
+		prev = *before
+	}
+
+	wasActive := make(map[string]bool, len(prev.LinkedEvents))
+	for _, ev := range prev.LinkedEvents {
+		wasActive[ev.ID] = ev.Active
+	}
+	current := make(map[string]bool, len(after.LinkedEvents))
+
+	added, ended := false, false
+	for _, ev := range after.LinkedEvents {
+		current[ev.ID] = true
+		active, known := wasActive[ev.ID]
+		if !known {
+			added = true
+		} else if active && !ev.Active {
+			ended = true
+		}
+	}
+
+	removed := false
+	for id := range wasActive {
+		if !current[id] {
+			removed = true
+		}
+	}
+
+	if added {
+		changes = append(changes, models.ChangeAddedEvent)
+	}
+	if removed {
+		changes = append(changes, models.ChangeRemovedEvent)
+	}
+	if ended {
+		changes = append(changes, models.ChangeEventEnded)
+	}
+	if hasNew(prev.Cameras, after.Cameras) {
+		changes = append(changes, models.ChangeNewCamera)
+	}
+	if hasNew(prev.Zones, after.Zones) {
+		changes = append(changes, models.ChangeNewZone)
+	}
+	if !slices.Equal(prev.CurrentZones, after.CurrentZones) {
+		changes = append(changes, models.ChangeCurrentZones)
+	}
+	if hasNew(prev.Objects, after.Objects) {
+		changes = append(changes, models.ChangeNewObject)
+	}
+	if prev.Severity != after.Severity {
+		changes = append(changes, models.ChangeSeverity)
+	}
+	if after.Loitering && !prev.Loitering {
+		changes = append(changes, models.ChangeLoitering)
+	}
+
+	return changes
+}
+
+// hasNew reports whether after contains a value missing from before
+func hasNew(before, after []string) bool {
+	for _, v := range after {
+		if !slices.Contains(before, v) {
+			return true
+		}
+	}
+	return false
+}
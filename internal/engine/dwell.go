@@ -0,0 +1,149 @@
+package engine
+
+import (
+	"slices"
+	"time"
+
+	"frigate-custom-reviews/internal/logger"
+	"frigate-custom-reviews/internal/models"
+)
+
+// zoneDwell tracks when an event's object entered each zone and how long it
+// stayed, following the current_zones of its updates
+type zoneDwell struct {
+	since   map[string]float64 // Start of the ongoing stay per zone
+	longest map[string]float64 // Longest finished stay per zone, in seconds
+	entered map[string]float64 // Start of the latest stay per zone, ongoing or not
+}
+
+// observe records the zones an update reports the object in, at the
+// update's frame time, or when it was received if it has none. An ended
+// event ends every stay.
+func (d *zoneDwell) observe(state models.FrigateEventState, received time.Time) {
+	if d.since == nil {
+		d.since = make(map[string]float64)
+		d.longest = make(map[string]float64)
+		d.entered = make(map[string]float64)
+	}
+
+	at := state.FrameTime
+	if at == 0 {
+		at = unixSeconds(received)
+	}
+	current := state.CurrentZones
+	if state.EndTime != 0 {
+		at = state.EndTime
+		current = nil
+	}
+
+	for zone, start := range d.since {
+		if !slices.Contains(current, zone) {
+			d.longest[zone] = max(d.longest[zone], at-start)
+			delete(d.since, zone)
+		}
+	}
+	for _, zone := range current {
+		if _, ok := d.since[zone]; !ok {
+			d.since[zone] = at
+			d.entered[zone] = at
+		}
+	}
+}
+
+// dwell returns the longest stay in any of zones, or in any zone if zones
+// is empty, counting an ongoing stay up to now
+func (d *zoneDwell) dwell(zones []string, now float64) float64 {
+	longest := 0.0
+	consider := func(zone string) bool {
+		return len(zones) == 0 || slices.Contains(zones, zone)
+	}
+	for zone, stay := range d.longest {
+		if consider(zone) {
+			longest = max(longest, stay)
+		}
+	}
+	for zone, start := range d.since {
+		if consider(zone) {
+			longest = max(longest, now-start)
+		}
+	}
+	return longest
+}
+
+// enteredSince reports whether the object entered one of zones at or after t
+func (d *zoneDwell) enteredSince(zones []string, t float64) bool {
+	for _, zone := range zones {
+		if at, ok := d.entered[zone]; ok && at >= t {
+			return true
+		}
+	}
+	return false
+}
+
+// dwellSeconds returns how long the event's object has stayed in one of zones
+func (e *Engine) dwellSeconds(id string, zones []string) float64 {
+	h, ok := e.history[id]
+	if !ok {
+		return 0
+	}
+	return h.dwell.dwell(zones, unixSeconds(e.now()))
+}
+
+// hasDwell reports whether dwell times apply to the event: only tracked
+// objects report the zones they are in over time
+func hasDwell(state models.FrigateEventState) bool {
+	return !state.IsPointInTime() && !state.IsReviewSegment()
+}
+
+// checkDwell adds events that reached a profile's min_dwell since their last
+// update, as a stationary object may not send another one. Events the
+// engine already ended, and events without dwell times, are left alone.
+func (e *Engine) checkDwell() {
+	var ids []string
+	for id, h := range e.history {
+		if h.last.After.EndTime == 0 && !h.closed && hasDwell(h.last.After) {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+
+	for _, profile := range e.profiles {
+		if profile.MinDwell <= 0 {
+			continue
+		}
+		for _, id := range ids {
+			if review, ok := e.activeReviews[profile.Name]; ok && review.Events[id] != nil {
+				continue
+			}
+			h := e.history[id]
+			if e.rejectReason(profile, h.last.After) == "" {
+				e.applyEvent(profile, h.last, h.lastSeen)
+			}
+		}
+	}
+}
+
+// checkLoitering flags the review once an object stayed in the profile's
+// required zones (or any zone) past its loiter_threshold. It reports
+// whether the flag was just set.
+func (e *Engine) checkLoitering(r *ReviewInstance) bool {
+	if r.Profile.LoiterThreshold <= 0 || r.Loitering {
+		return false
+	}
+
+	for id, tracked := range r.Events {
+		if !hasDwell(tracked.Event.After) {
+			continue
+		}
+		if e.dwellSeconds(id, r.Profile.RequiredZones) >= float64(r.Profile.LoiterThreshold) {
+			r.Loitering = true
+			logger.Infof("Review %s (Profile: %s): event %s is loitering", r.ID, r.Profile.Name, id)
+			return true
+		}
+	}
+	return false
+}
+
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / 1e9
+}
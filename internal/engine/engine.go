@@ -7,12 +7,24 @@ import (
 	"strings"
 	"time"
 
+	"frigate-custom-reviews/internal/audit"
+	"frigate-custom-reviews/internal/clock"
 	"frigate-custom-reviews/internal/logger"
 	"frigate-custom-reviews/internal/models"
 
 	"github.com/google/uuid"
 )
 
+// defaultGhostTimeout matches the event_timeout config default
+const defaultGhostTimeout = 300 * time.Second
+
+// Bounds of the Run tick interval, which shortens to flush held back
+// updates of profiles with an update_interval_ms below a second
+const (
+	maxTickInterval = time.Second
+	minTickInterval = 100 * time.Millisecond
+)
+
 type EngineOption func(*Engine)
 
 func WithPublishUpdates(publish bool) EngineOption {
@@ -27,28 +39,128 @@ func WithGhostTimeout(timeout int) EngineOption {
 	}
 }
 
-func NewEngine(profiles []models.Profile, mqttClient MQTTPublisher, publishTopic string, opts ...EngineOption) *Engine {
+// WithAuditor records every ingested event, profile decision and review
+// state transition
+func WithAuditor(a Auditor) EngineOption {
+	return func(e *Engine) {
+		e.auditor = a
+	}
+}
+
+// WithClock replaces the wall clock used for LastSeen, ghost detection, gap
+// evaluation and the Run ticker, e.g. with a clock.Virtual for replays and tests
+func WithClock(c clock.Clock) EngineOption {
+	return func(e *Engine) {
+		e.clock = c
+	}
+}
+
+func NewEngine(profiles []models.Profile, publisher Publisher, opts ...EngineOption) *Engine {
 	engine := &Engine{
 		profiles:      profiles,
 		activeReviews: make(map[string]*ReviewInstance),
+		history:       make(map[string]*eventHistory),
 		ingestChan:    make(chan models.FrigateEvent, 100),
-		mqttClient:    mqttClient,
-		publishTopic:  publishTopic,
+		control:       make(chan func()),
+		publisher:     publisher,
+		clock:         clock.Real(),
+		ghostTimeout:  defaultGhostTimeout,
 	}
 
 	for _, opt := range opts {
 		opt(engine)
 	}
+	engine.tickInterval = tickIntervalFor(profiles)
 
 	return engine
 }
 
+// tickIntervalFor returns the shortest profile update interval, clamped to
+// [minTickInterval, maxTickInterval]
+func tickIntervalFor(profiles []models.Profile) time.Duration {
+	interval := maxTickInterval
+	for _, p := range profiles {
+		if p.UpdateInterval <= 0 {
+			continue
+		}
+		interval = min(interval, time.Duration(p.UpdateInterval)*time.Millisecond)
+	}
+	return max(interval, minTickInterval)
+}
+
+// TickInterval is how often Run performs the periodic checks. Offline
+// drivers should call Tick at the same rate.
+func (e *Engine) TickInterval() time.Duration {
+	return e.tickInterval
+}
+
 func (e *Engine) IngestChannel() chan<- models.FrigateEvent {
 	return e.ingestChan
 }
 
+// Step processes a single event synchronously. It is meant for offline
+// drivers such as replay and must not be used while Run is active.
+func (e *Engine) Step(evt models.FrigateEvent) {
+	e.handleEvent(evt)
+}
+
+// Tick runs the periodic ghost, escalation and gap checks synchronously.
+// Like Step, it must not be used while Run is active.
+func (e *Engine) Tick() {
+	e.handleTick()
+}
+
+// ActiveReviews returns the number of reviews that have not ended yet.
+// Like Step, it must not be used while Run is active.
+func (e *Engine) ActiveReviews() int {
+	return len(e.activeReviews)
+}
+
+// ActiveEvents returns the last known state of every event tracked in an
+// active review that has not ended yet. It is answered by the Run loop and
+// blocks until Run is active.
+func (e *Engine) ActiveEvents() []models.FrigateEventState {
+	result := make(chan []models.FrigateEventState, 1)
+	e.control <- func() {
+		result <- e.activeEvents()
+	}
+	return <-result
+}
+
+// Recover processes events from the Frigate API that rebuild the reviews
+// after a restart, in order. An event that already ended only joins the
+// profiles whose gap has not run out since, as the others' reviews would
+// have closed before the restart. It is answered by the Run loop and blocks
+// until Run is active and the events are processed.
+func (e *Engine) Recover(events []models.FrigateEvent) {
+	done := make(chan struct{})
+	e.control <- func() {
+		for _, evt := range events {
+			e.processEvent(evt, true)
+		}
+		close(done)
+	}
+	<-done
+}
+
+func (e *Engine) activeEvents() []models.FrigateEventState {
+	seen := make(map[string]bool)
+	var states []models.FrigateEventState
+	for _, review := range e.activeReviews {
+		for id, tracked := range review.Events {
+			// The same event may be tracked by several profiles
+			if seen[id] || tracked.Event.After.EndTime != 0 {
+				continue
+			}
+			seen[id] = true
+			states = append(states, tracked.Event.After)
+		}
+	}
+	return states
+}
+
 func (e *Engine) Run() {
-	ticker := time.NewTicker(1 * time.Second)
+	ticker := e.clock.NewTicker(e.tickInterval)
 	defer ticker.Stop()
 
 	logger.Info("Engine started")
@@ -57,153 +169,326 @@ func (e *Engine) Run() {
 		select {
 		case evt := <-e.ingestChan:
 			e.handleEvent(evt)
-		case <-ticker.C:
+		case <-ticker.C():
 			e.handleTick()
+		case fn := <-e.control:
+			fn()
 		}
 	}
 }
 
 func (e *Engine) handleEvent(evt models.FrigateEvent) {
-	state := evt.After
+	e.processEvent(evt, false)
+}
+
+// processEvent admits evt and applies it to every profile. While recovering,
+// profiles whose gap ran out since the event ended are skipped.
+func (e *Engine) processEvent(evt models.FrigateEvent, recovering bool) {
+	e.record(audit.Record{Kind: audit.KindEvent, EventID: evt.After.ID, Event: &evt})
+
+	if !e.admit(&evt) {
+		return
+	}
 
 	for _, profile := range e.profiles {
-		if !e.matchesProfile(profile, state) {
-			logger.Debugf("Rejectd Event: ID: %v, Camera: %v, Label: %v, Zones: %v",
-				evt.After.ID,
-				evt.After.Camera,
-				evt.After.Label,
-				evt.After.EnteredZones,
-			)
+		if recovering && gapExpired(profile, evt.After, e.now()) {
+			logger.Debugf("Skipped recovered event %v for profile %v, its gap has run out", evt.After.ID, profile.Name)
+			e.record(audit.Record{Kind: audit.KindReject, EventID: evt.After.ID, Profile: profile.Name, Criterion: criterionGap})
 			continue
-		} else {
-			logger.Debugf("Matched Event: ID: %v, Camera: %v, Label: %v, Zones: %v",
-				evt.After.ID,
-				evt.After.Camera,
-				evt.After.Label,
-				evt.After.EnteredZones,
-			)
 		}
+		e.applyEvent(profile, evt, e.now())
+	}
+}
 
-		review, exists := e.activeReviews[profile.Name]
+// gapExpired reports whether the profile's gap has run out since the event
+// ended, taking point-in-time events to end point_duration after they started
+func gapExpired(p models.Profile, state models.FrigateEventState, now time.Time) bool {
+	end := state.EndTime
+	if state.IsPointInTime() {
+		end = state.StartTime + float64(p.PointDuration)
+	}
+	if end == 0 {
+		return false
+	}
+	return now.Sub(time.Unix(int64(end), 0)).Seconds() > float64(p.Gap)
+}
 
-		if !exists {
-			review = &ReviewInstance{
-				ID:           uuid.NewString(),
-				Profile:      profile,
-				Events:       make(map[string]*TrackedEvent),
-				State:        "active",
-				LastUpdated:  time.Now(),
-				LastEventEnd: time.Time{},
-			}
-			e.activeReviews[profile.Name] = review
-		} else {
-			review.State = "active"
-		}
+// applyEvent adds evt to the profile's review if it matches the profile, or
+// removes it if it no longer does. seen is when the event was last heard of.
+func (e *Engine) applyEvent(profile models.Profile, evt models.FrigateEvent, seen time.Time) {
+	state := evt.After
 
-		var beforeState *models.ReviewState
-		if review.SentFirstEvent {
-			s := e.toReviewState(review)
-			beforeState = &s
+	if criterion := e.rejectReason(profile, state); criterion != "" {
+		logger.Debugf("Rejectd Event: ID: %v, Camera: %v, Label: %v, Zones: %v, Criterion: %v",
+			evt.After.ID,
+			evt.After.QualifiedCamera(),
+			evt.After.Label,
+			evt.After.EnteredZones,
+			criterion,
+		)
+		e.record(audit.Record{Kind: audit.KindReject, EventID: state.ID, Profile: profile.Name, Criterion: criterion})
+		e.removeFromReview(profile, state, criterion)
+		return
+	}
+	logger.Debugf("Matched Event: ID: %v, Camera: %v, Label: %v, Zones: %v",
+		evt.After.ID,
+		evt.After.QualifiedCamera(),
+		evt.After.Label,
+		evt.After.EnteredZones,
+	)
+	e.record(audit.Record{Kind: audit.KindMatch, EventID: state.ID, Profile: profile.Name})
+
+	review, exists := e.activeReviews[profile.Name]
+
+	if !exists {
+		review = &ReviewInstance{
+			ID:           uuid.NewString(),
+			Profile:      profile,
+			Events:       make(map[string]*TrackedEvent),
+			State:        "active",
+			OpenedAt:     e.now(),
+			LastUpdated:  e.now(),
+			LastEventEnd: time.Time{},
 		}
+		e.activeReviews[profile.Name] = review
+		e.recordTransition(review, "", review.State, "created by event "+state.ID)
+	} else if review.State != "active" {
+		e.recordTransition(review, review.State, "active", "reopened by event "+state.ID)
+		review.State = "active"
+	}
+
+	beforeState := review.LastPublished
 
-		// Update Review State
-		evtCopy := evt
-		review.Events[state.ID] = &TrackedEvent{
-			Event:    &evtCopy,
-			LastSeen: time.Now(),
+	// Update Review State
+	evtCopy := evt
+	if state.IsPointInTime() {
+		// Ends point_duration after it started, so the gap runs from
+		// shortly after the sound or external trigger
+		evtCopy.After.EndTime = state.StartTime + float64(profile.PointDuration)
+	}
+	if leftRequiredZones(profile, state) {
+		// Its part of the review ended when it left, even though Frigate may
+		// track the object for a while longer
+		if prev := review.Events[state.ID]; prev != nil && prev.Event.After.EndTime != 0 {
+			evtCopy.After.EndTime = prev.Event.After.EndTime
+		} else if evtCopy.After.EndTime == 0 {
+			evtCopy.After.EndTime = state.FrameTime
+			if evtCopy.After.EndTime == 0 {
+				evtCopy.After.EndTime = unixSeconds(e.now())
+			}
 		}
-		review.LastUpdated = time.Now()
+	}
+	review.Events[state.ID] = &TrackedEvent{
+		Event:    &evtCopy,
+		LastSeen: seen,
+	}
+	review.LastUpdated = e.now()
+	e.checkLoitering(review)
 
-		afterState := e.toReviewState(review)
+	afterState := e.toReviewState(review)
 
-		payloadType := "new"
-		if review.SentFirstEvent {
-			payloadType = "update"
-		}
+	payloadType := models.MessageNew
+	if review.SentFirstEvent {
+		payloadType = models.MessageUpdate
+	}
 
-		if beforeState != nil && afterState.ActiveEvents != beforeState.ActiveEvents {
-			logger.Infof("Review %v has changed number of active events %v => %v", review.ID, beforeState.ActiveEvents, afterState.ActiveEvents)
+	if beforeState != nil && afterState.ActiveEvents != beforeState.ActiveEvents {
+		logger.Infof("Review %v has changed number of active events %v => %v", review.ID, beforeState.ActiveEvents, afterState.ActiveEvents)
+	}
+
+	if payloadType == models.MessageUpdate {
+		if e.publishUpdates {
+			e.updateReview(review)
 		}
+		return
+	}
+
+	e.publish(review, payloadType, afterState, nil)
+}
 
-		if !e.publishUpdates && payloadType == "update" {
+// publish sends a lifecycle message for review, with the state of the
+// previous message as 'before'
+func (e *Engine) publish(review *ReviewInstance, msgType string, after models.ReviewState, removed []models.RemovedEvent) {
+	e.publisher.Publish(models.MessagePayload{
+		Type:          msgType,
+		Before:        review.LastPublished,
+		After:         &after,
+		RemovedEvents: removed,
+		Changes:       diffReviewStates(review.LastPublished, &after),
+	})
+	logger.Debugf("Emitted '%s' for Review %s (Profile: %s). Events: %d",
+		msgType, review.ID, review.Profile.Name, len(review.Events))
+
+	review.LastPublished = &after
+	review.LastPublishAt = e.now()
+	review.PendingUpdate = false
+	review.SentFirstEvent = true
+}
+
+// updateReview publishes an 'update' when the review summary differs from
+// the last message. Within the profile's update interval the update is held
+// back and flushed by a later event or tick, so bursts are merged.
+func (e *Engine) updateReview(review *ReviewInstance) {
+	after := e.toReviewState(review)
+	if len(diffReviewStates(review.LastPublished, &after)) == 0 {
+		review.PendingUpdate = false
+		return
+	}
+
+	interval := time.Duration(review.Profile.UpdateInterval) * time.Millisecond
+	if interval > 0 && e.now().Sub(review.LastPublishAt) < interval {
+		review.PendingUpdate = true
+		return
+	}
+
+	e.publish(review, models.MessageUpdate, after, nil)
+}
+
+// removeFromReview drops an event that no longer matches profile from the
+// profile's active review. A review left without events is discarded if it
+// was never published, or ended otherwise.
+func (e *Engine) removeFromReview(profile models.Profile, state models.FrigateEventState, reason string) {
+	review, exists := e.activeReviews[profile.Name]
+	if !exists {
+		return
+	}
+	if _, tracked := review.Events[state.ID]; !tracked {
+		return
+	}
+
+	startTime := e.toReviewState(review).StartTime
+
+	delete(review.Events, state.ID)
+	review.LastUpdated = e.now()
+	logger.Infof("Removed event %s from review %s (Profile: %s): %s", state.ID, review.ID, profile.Name, reason)
+	e.record(audit.Record{
+		Kind:     audit.KindTransition,
+		EventID:  state.ID,
+		Profile:  profile.Name,
+		ReviewID: review.ID,
+		From:     "tracked",
+		To:       "removed",
+		Reason:   reason,
+	})
+
+	removed := []models.RemovedEvent{{ID: state.ID, Camera: state.QualifiedCamera(), Reason: reason}}
+
+	if len(review.Events) == 0 {
+		delete(e.activeReviews, profile.Name)
+
+		if !review.SentFirstEvent {
+			e.recordTransition(review, review.State, "discarded", "no qualifying events left")
+			logger.Infof("Discarded review %s (Profile: %s): no qualifying events left", review.ID, profile.Name)
 			return
 		}
 
-		msg := models.MessagePayload{
-			Type:   payloadType,
-			Before: beforeState,
-			After:  &afterState,
-		}
+		e.recordTransition(review, review.State, "ended", "no qualifying events left")
+		review.State = "ended"
 
-		if err := e.mqttClient.Publish(e.publishTopic, msg); err != nil {
-			logger.Errorf("Error publishing review update: %v", err)
-		} else {
-			logger.Infof("[MQTT] Published '%s' for Review %s (Profile: %s). Events: %d",
-				payloadType, review.ID, review.Profile.Name, len(review.Events))
-			review.SentFirstEvent = true
-		}
+		// An empty review has no events to derive times from, keep the
+		// original start and end it now
+		afterState := e.toReviewState(review)
+		afterState.StartTime = startTime
+		endTime := float64(e.now().Unix())
+		afterState.EndTime = &endTime
+
+		e.publish(review, models.MessageEnd, afterState, removed)
+		return
 	}
+
+	if !review.SentFirstEvent || !e.publishUpdates {
+		return
+	}
+
+	// Removals bypass the update interval so removed_events is never merged away
+	e.publish(review, models.MessageUpdate, e.toReviewState(review), removed)
 }
 
 func (e *Engine) handleTick() {
+	e.pruneHistory()
+	e.checkDwell()
+
 	for name, review := range e.activeReviews {
 		// 1. Check for Ghost Events
 		updatedReview := false
 		for id, tracked := range review.Events {
 			// If event is active (EndTime == 0) and stale
-			if tracked.Event.After.EndTime == 0 && time.Since(tracked.LastSeen) > e.ghostTimeout {
+			if tracked.Event.After.EndTime == 0 && e.now().Sub(tracked.LastSeen) > e.ghostTimeout {
 				logger.Noticef("Ghost event detected: %s in review %s. Closing event.", id, review.ID)
-				logger.Debugf("%v, %v ", time.Since(tracked.LastSeen), e.ghostTimeout)
+				logger.Debugf("%v, %v ", e.now().Sub(tracked.LastSeen), e.ghostTimeout)
 
 				// Force close the event
 				// We set EndTime to the timestamp of when it went stale (approx now)
-				nowUnix := float64(time.Now().Unix())
+				nowUnix := float64(e.now().Unix())
 				tracked.Event.After.EndTime = nowUnix
+				if h, ok := e.history[id]; ok {
+					h.closed = true
+				}
 				// We don't update LastSeen as we want it to remain 'processed'
 				updatedReview = true
+				e.record(audit.Record{
+					Kind:     audit.KindTransition,
+					EventID:  id,
+					Profile:  review.Profile.Name,
+					ReviewID: review.ID,
+					From:     "active",
+					To:       "ended",
+					Reason:   "ghost timeout",
+				})
 			}
 		}
 
-		if updatedReview && e.publishUpdates {
-			// If we modified events, we should publish an update
-			// (Use 'update' message)
-			currentState := e.toReviewState(review)
-			msg := models.MessagePayload{
-				Type:   "update",
-				Before: nil, // We could calculate before, but for ghost cleanup current state is vital
-				After:  &currentState,
-			}
-			if err := e.mqttClient.Publish(e.publishTopic, msg); err == nil {
-				logger.Infof("[MQTT] Published 'update' (ghost cleanup) for Review %s", review.ID)
-			}
+		// Publish ghost cleanup or an update held back by the update interval
+		if (updatedReview || review.PendingUpdate) && e.publishUpdates && review.SentFirstEvent {
+			e.updateReview(review)
+		}
+
+		// 2. Check for objects loitering past the profile's threshold. An
+		// escalated review reports it in an update instead.
+		if e.checkLoitering(review) && review.Escalated && e.publishUpdates {
+			e.updateReview(review)
 		}
 
-		// 2. Check if we should close the review
+		// 3. Check whether the review has been running long enough, or has
+		// loitering, to escalate
+		if e.shouldEscalate(review) {
+			e.publish(review, models.MessageEscalate, e.toReviewState(review), nil)
+			review.Escalated = true
+			logger.Infof("Escalated review %s (Profile: %s), loitering: %v", review.ID, name, review.Loitering)
+		}
+
+		// 4. Check if we should close the review
 		if e.shouldClose(review) {
 			logger.Infof("Closing review %s (Profile: %s)", review.ID, name)
 
-			beforeState := e.toReviewState(review)
-
+			e.recordTransition(review, review.State, "ended", "gap expired")
 			review.State = "ended"
-			afterState := e.toReviewState(review)
-
-			msg := models.MessagePayload{
-				Type:   "end",
-				Before: &beforeState,
-				After:  &afterState,
-			}
-
-			if err := e.mqttClient.Publish(e.publishTopic, msg); err != nil {
-				logger.Errorf("Error publishing review end: %v", err)
-			} else {
-				logger.Infof("[MQTT] Published 'end' for Review %s (Profile: %s)", review.ID, name)
-			}
+			e.publish(review, models.MessageEnd, e.toReviewState(review), nil)
 
 			delete(e.activeReviews, name)
 		}
 	}
 }
 
+// shouldEscalate reports whether a review has stayed active past its
+// profile's escalate_after threshold, or is loitering, without being
+// escalated yet.
+func (e *Engine) shouldEscalate(r *ReviewInstance) bool {
+	if r.Escalated || !r.SentFirstEvent {
+		return false
+	}
+	if r.Loitering {
+		return true
+	}
+	if r.Profile.EscalateAfter <= 0 {
+		return false
+	}
+
+	start := e.toReviewState(r).StartTime
+	started := time.Unix(int64(start), 0)
+	return e.now().Sub(started).Seconds() >= float64(r.Profile.EscalateAfter)
+}
+
 func (e *Engine) shouldClose(r *ReviewInstance) bool {
 	activeCount := 0
 	var maxEndTime float64 = 0
@@ -225,17 +510,55 @@ func (e *Engine) shouldClose(r *ReviewInstance) bool {
 	}
 
 	if r.State != "pending-close" {
+		e.recordTransition(r, r.State, "pending-close", "all events ended")
 		r.State = "pending-close"
 		logger.Infof("Entering Gap for review %s", r.ID)
 	}
 
 	lastEnd := time.Unix(int64(maxEndTime), 0)
-	waited := time.Since(lastEnd)
+	waited := e.now().Sub(lastEnd)
 
 	return waited.Seconds() > float64(r.Profile.Gap)
 }
 
-func zonesOverlap(a, b []string) bool {
+// cameraMatches reports whether a profile camera refers to the event's
+// camera: "garage/driveway" matches that source only, a bare "driveway"
+// matches the camera on any source
+func cameraMatches(camera string, state models.FrigateEventState) bool {
+	return camera == state.Camera || camera == state.QualifiedCamera()
+}
+
+// inRequiredZone checks the event's zones against the profile's required
+// zones according to its zone_mode. The zone mode decides whether an event
+// can join the review; an event that joined stays when it moves on.
+func (e *Engine) inRequiredZone(p models.Profile, state models.FrigateEventState) bool {
+	review, open := e.activeReviews[p.Name]
+	if open && review.Events[state.ID] != nil {
+		return true
+	}
+
+	switch p.ZoneMode {
+	case models.ZoneModeCurrent:
+		return anyOverlap(p.RequiredZones, state.CurrentZones)
+	case models.ZoneModeSinceReview:
+		if !open {
+			// The event would open the review itself
+			return anyOverlap(p.RequiredZones, state.EnteredZones)
+		}
+		h, ok := e.history[state.ID]
+		return ok && h.dwell.enteredSince(p.RequiredZones, unixSeconds(review.OpenedAt))
+	}
+	return anyOverlap(p.RequiredZones, state.EnteredZones)
+}
+
+// leftRequiredZones reports whether a tracked event of a zone_mode current
+// profile is no longer in any required zone
+func leftRequiredZones(p models.Profile, state models.FrigateEventState) bool {
+	return p.ZoneMode == models.ZoneModeCurrent && len(p.RequiredZones) > 0 && !state.IsPointInTime() &&
+		!anyOverlap(p.RequiredZones, state.CurrentZones)
+}
+
+func anyOverlap(a, b []string) bool {
 	for _, v := range a {
 		if slices.Contains(b, v) {
 			return true
@@ -309,31 +632,110 @@ func matchesTimeRanges(ranges []models.TimeRange, eventTime float64) (bool, bool
 	return false, validFound
 }
 
+// Profile criteria reported when an event is rejected
+const (
+	criterionFalsePositive = "false_positive"
+	criterionInput         = "input"
+	criterionSeverity      = "severity"
+	criterionCamera        = "camera"
+	criterionLabel         = "label"
+	criterionSubLabel      = "sub_label"
+	criterionZone          = "zone"
+	criterionDwell         = "dwell"
+	criterionTimeRange     = "time_range"
+	criterionGap           = "gap" // Recovery only: the event ended more than gap ago
+)
+
 func (e *Engine) matchesProfile(p models.Profile, state models.FrigateEventState) bool {
-	if len(p.Cameras) > 0 && !slices.Contains(p.Cameras, state.Camera) {
-		return false
+	return e.rejectReason(p, state) == ""
+}
+
+// rejectReason returns the first profile criterion the event fails, or an
+// empty string if the event matches the profile.
+func (e *Engine) rejectReason(p models.Profile, state models.FrigateEventState) string {
+	if state.FalsePositive {
+		return criterionFalsePositive
 	}
 
-	if len(p.Labels) > 0 && !slices.Contains(p.Labels, state.Label) {
-		return false
+	if state.IsReviewSegment() != (p.Input == models.InputReviews) {
+		return criterionInput
 	}
 
-	if len(p.RequiredZones) > 0 && len(state.EnteredZones) > 0 && !zonesOverlap(p.RequiredZones, state.EnteredZones) {
-		return false
+	if len(p.Severities) > 0 && !slices.Contains(p.Severities, state.Severity) {
+		return criterionSeverity
 	}
 
-	if len(p.RequiredZones) > 0 && len(state.EnteredZones) == 0 {
-		return false
+	if len(p.Cameras) > 0 && !slices.ContainsFunc(p.Cameras, func(camera string) bool {
+		return cameraMatches(camera, state)
+	}) {
+		return criterionCamera
+	}
+
+	if len(p.Labels)+len(p.CustomLabels) > 0 && !anyOverlap(p.Labels, state.Labels()) && !anyOverlap(p.CustomLabels, state.Labels()) {
+		return criterionLabel
+	}
+
+	if len(p.SubLabels) > 0 && !anyOverlap(p.SubLabels, state.SubLabelNames()) {
+		return criterionSubLabel
+	}
+
+	// Audio and external events have no position, so with required zones
+	// they only match if the profile lists their label explicitly
+	if len(p.RequiredZones) > 0 {
+		if state.IsPointInTime() {
+			if !anyOverlap(p.Labels, state.Labels()) && !anyOverlap(p.CustomLabels, state.Labels()) {
+				return criterionZone
+			}
+		} else if !e.inRequiredZone(p, state) {
+			return criterionZone
+		}
+	}
+
+	if p.MinDwell > 0 && hasDwell(state) && e.dwellSeconds(state.ID, p.RequiredZones) < float64(p.MinDwell) {
+		return criterionDwell
 	}
 
 	if len(p.TimeRanges) > 0 {
 		matches, hasValid := matchesTimeRanges(p.TimeRanges, state.StartTime)
 		if !hasValid || !matches {
-			return false
+			return criterionTimeRange
 		}
 	}
 
-	return true
+	return ""
+}
+
+// severityRank orders Frigate severities, unknown and empty ones lowest
+func severityRank(severity string) int {
+	switch severity {
+	case models.SeverityAlert:
+		return 2
+	case models.SeverityDetection:
+		return 1
+	}
+	return 0
+}
+
+func (e *Engine) now() time.Time {
+	return e.clock.Now()
+}
+
+func (e *Engine) record(rec audit.Record) {
+	if e.auditor != nil {
+		rec.Time = e.now()
+		e.auditor.Record(rec)
+	}
+}
+
+func (e *Engine) recordTransition(r *ReviewInstance, from, to, reason string) {
+	e.record(audit.Record{
+		Kind:     audit.KindTransition,
+		Profile:  r.Profile.Name,
+		ReviewID: r.ID,
+		From:     from,
+		To:       to,
+		Reason:   reason,
+	})
 }
 
 func (e *Engine) toReviewState(r *ReviewInstance) models.ReviewState {
@@ -344,6 +746,8 @@ func (e *Engine) toReviewState(r *ReviewInstance) models.ReviewState {
 	objectsSet := make(map[string]bool)
 	camerasSet := make(map[string]bool)
 	zonesSet := make(map[string]bool)
+	currentZonesSet := make(map[string]bool)
+	severity := ""
 
 	first := true
 	allEnded := true
@@ -367,13 +771,24 @@ func (e *Engine) toReviewState(r *ReviewInstance) models.ReviewState {
 
 		linkedEvents = append(linkedEvents, models.LinkedEventSummary{
 			ID:     state.ID,
-			Camera: state.Camera,
+			Camera: state.QualifiedCamera(),
+			Active: state.EndTime == 0,
 		})
-		objectsSet[state.Label] = true
-		camerasSet[state.Camera] = true
+		for _, label := range state.Labels() {
+			objectsSet[label] = true
+		}
+		camerasSet[state.QualifiedCamera()] = true
+		if severityRank(state.Severity) > severityRank(severity) {
+			severity = state.Severity
+		}
 		for _, zone := range state.EnteredZones {
 			zonesSet[zone] = true
 		}
+		if state.EndTime == 0 {
+			for _, zone := range state.CurrentZones {
+				currentZonesSet[zone] = true
+			}
+		}
 
 		first = false
 	}
@@ -393,6 +808,20 @@ func (e *Engine) toReviewState(r *ReviewInstance) models.ReviewState {
 		zones = append(zones, k)
 	}
 
+	currentZones := []string{}
+	for k := range currentZonesSet {
+		currentZones = append(currentZones, k)
+	}
+
+	// Sorted so states compare and serialize the same regardless of map order
+	slices.Sort(objects)
+	slices.Sort(cameras)
+	slices.Sort(zones)
+	slices.Sort(currentZones)
+	slices.SortFunc(linkedEvents, func(a, b models.LinkedEventSummary) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+
 	out := models.ReviewState{
 		ID:           r.ID,
 		ProfileName:  r.Profile.Name,
@@ -404,6 +833,9 @@ func (e *Engine) toReviewState(r *ReviewInstance) models.ReviewState {
 		Objects:      objects,
 		Cameras:      cameras,
 		Zones:        zones,
+		CurrentZones: currentZones,
+		Severity:     severity,
+		Loitering:    r.Loitering,
 	}
 
 	if allEnded && len(r.Events) > 0 {
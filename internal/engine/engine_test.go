@@ -1,45 +1,54 @@
 package engine
 
 import (
-	"fmt"
+	"encoding/json"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
+	"frigate-custom-reviews/internal/audit"
+	"frigate-custom-reviews/internal/clock"
 	"frigate-custom-reviews/internal/models"
 )
 
-// MockMQTTPublisher captures messages for verification
-type MockMQTTPublisher struct {
-	PublishedMessages []struct {
-		Topic   string
-		Payload models.MessagePayload
-	}
+// MockPublisher captures messages for verification
+type MockPublisher struct {
+	PublishedMessages []models.MessagePayload
 }
 
-func (m *MockMQTTPublisher) Publish(topic string, payload interface{}) error {
-	msg, ok := payload.(models.MessagePayload)
-	if !ok {
-		return fmt.Errorf("invalid payload type")
-	}
-	m.PublishedMessages = append(m.PublishedMessages, struct {
-		Topic   string
-		Payload models.MessagePayload
-	}{Topic: topic, Payload: msg})
-	return nil
+func (m *MockPublisher) Publish(msg models.MessagePayload) {
+	m.PublishedMessages = append(m.PublishedMessages, msg)
 }
 
-func (m *MockMQTTPublisher) LastMessage() *models.MessagePayload {
+func (m *MockPublisher) LastMessage() *models.MessagePayload {
 	if len(m.PublishedMessages) == 0 {
 		return nil
 	}
-	return &m.PublishedMessages[len(m.PublishedMessages)-1].Payload
+	return &m.PublishedMessages[len(m.PublishedMessages)-1]
+}
+
+func (m *MockPublisher) Clear() {
+	m.PublishedMessages = []models.MessagePayload{}
+}
+
+// MockAuditor captures audit records for verification
+type MockAuditor struct {
+	Records []audit.Record
 }
 
-func (m *MockMQTTPublisher) Clear() {
-	m.PublishedMessages = []struct {
-		Topic   string
-		Payload models.MessagePayload
-	}{}
+func (m *MockAuditor) Record(rec audit.Record) {
+	m.Records = append(m.Records, rec)
+}
+
+func (m *MockAuditor) Kind(kind string) []audit.Record {
+	var out []audit.Record
+	for _, rec := range m.Records {
+		if rec.Kind == kind {
+			out = append(out, rec)
+		}
+	}
+	return out
 }
 
 func TestMatchesProfile(t *testing.T) {
@@ -160,6 +169,39 @@ func TestMatchesProfile(t *testing.T) {
 			},
 			want: true,
 		},
+		{
+			name: "Sub Label Match",
+			profile: models.Profile{
+				Labels:    []string{"person"},
+				SubLabels: []string{"alice"},
+			},
+			state: models.FrigateEventState{
+				Label:    "person",
+				SubLabel: "alice",
+			},
+			want: true,
+		},
+		{
+			name: "Sub Label Mismatch",
+			profile: models.Profile{
+				SubLabels: []string{"alice"},
+			},
+			state: models.FrigateEventState{
+				Label: "person",
+			},
+			want: false,
+		},
+		{
+			name: "False Positive",
+			profile: models.Profile{
+				Cameras: []string{"cam1"},
+			},
+			state: models.FrigateEventState{
+				Camera:        "cam1",
+				FalsePositive: true,
+			},
+			want: false,
+		},
 		{
 			name: "Empty Profile (Wildcard)",
 			profile: models.Profile{
@@ -202,6 +244,65 @@ func TestMatchesProfile(t *testing.T) {
 			},
 			want: true,
 		},
+		{
+			name:    "Zone Mode Current Requires Occupied Zone",
+			profile: models.Profile{RequiredZones: []string{"porch"}, ZoneMode: models.ZoneModeCurrent},
+			state: models.FrigateEventState{
+				Camera:       "cam1",
+				EnteredZones: []string{"porch"},
+				CurrentZones: []string{"yard"},
+			},
+			want: false,
+		},
+		{
+			name:    "Zone Mode Current Match",
+			profile: models.Profile{RequiredZones: []string{"porch"}, ZoneMode: models.ZoneModeCurrent},
+			state: models.FrigateEventState{
+				Camera:       "cam1",
+				EnteredZones: []string{"yard", "porch"},
+				CurrentZones: []string{"porch"},
+			},
+			want: true,
+		},
+		{
+			name:    "Review Segment Ignored By Events Profile",
+			profile: models.Profile{Labels: []string{"person"}},
+			state: models.FrigateEventState{
+				Camera:   "cam1",
+				Label:    "person",
+				Severity: models.SeverityAlert,
+				Objects:  []string{"person"},
+			},
+			want: false,
+		},
+		{
+			name: "Review Segment Severity And Any Label",
+			profile: models.Profile{
+				Input:      models.InputReviews,
+				Labels:     []string{"car"},
+				Severities: []string{models.SeverityAlert},
+			},
+			state: models.FrigateEventState{
+				Camera:   "cam1",
+				Label:    "person",
+				Severity: models.SeverityAlert,
+				Objects:  []string{"person", "car"},
+			},
+			want: true,
+		},
+		{
+			name: "Review Segment Severity Mismatch",
+			profile: models.Profile{
+				Input:      models.InputReviews,
+				Severities: []string{models.SeverityAlert},
+			},
+			state: models.FrigateEventState{
+				Camera:   "cam1",
+				Severity: models.SeverityDetection,
+				Objects:  []string{"person"},
+			},
+			want: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -214,7 +315,7 @@ func TestMatchesProfile(t *testing.T) {
 }
 
 func TestEngine_Lifecycle(t *testing.T) {
-	mockMQTT := &MockMQTTPublisher{}
+	mockMQTT := &MockPublisher{}
 	profile := models.Profile{
 		Name:    "test_profile",
 		Cameras: []string{"cam1"},
@@ -222,7 +323,8 @@ func TestEngine_Lifecycle(t *testing.T) {
 		Gap:     1,
 	}
 
-	engine := NewEngine([]models.Profile{profile}, mockMQTT, "test/review", WithPublishUpdates(true))
+	clk := clock.NewVirtual(time.Unix(1000, 0))
+	engine := NewEngine([]models.Profile{profile}, mockMQTT, WithPublishUpdates(true), WithClock(clk))
 
 	// Phase 1: Start Event A
 	evtA := models.FrigateEvent{
@@ -270,32 +372,35 @@ func TestEngine_Lifecycle(t *testing.T) {
 	engine.handleEvent(evtA) // Updates state
 	engine.handleEvent(evtB)
 
-	// Check ReviewState
-	// We need to verify internal state or message payload
-	// The messages were updates.
-
 	// Phase 4: Gap Expiry
-	// Gap is 1s. Last end time is 1015 (timestamp).
-	// Wait, the engine logic uses time.Now() vs LastEnd.
-	// LastEnd in `toReviewState` comes from `evt.After.EndTime`.
-	// `shouldClose` compares `time.Since(time.Unix(maxEndTime))` > Gap.
-	// Since maxEndTime (1015) is way in the past relative to time.Now() (2025/2026), it should close immediately on tick.
-	// To test properly with timestamps, we should use current timestamps.
+	// Gap is 1s and the last event ended at 1015, so the review must stay
+	// open at 1016 and close on the next tick.
+	clk.Set(time.Unix(1016, 0))
+	engine.handleTick()
+	if mockMQTT.LastMessage().Type == models.MessageEnd {
+		t.Fatal("Review closed before the gap expired")
+	}
 
-	// Clean slate for strict timing test
+	clk.Set(time.Unix(1017, 0))
+	engine.handleTick()
+	if mockMQTT.LastMessage().Type != models.MessageEnd {
+		t.Errorf("Expected 'end', got %s", mockMQTT.LastMessage().Type)
+	}
+	if end := mockMQTT.LastMessage().After.EndTime; end == nil || *end != 1015 {
+		t.Errorf("Expected review end time 1015, got %v", end)
+	}
 }
 
 func TestEngine_GapLogic(t *testing.T) {
-	mockMQTT := &MockMQTTPublisher{}
+	mockMQTT := &MockPublisher{}
 	profile := models.Profile{
 		Name:    "test_gap",
 		Cameras: []string{"cam1"},
 		Labels:  []string{"person"},
-		Gap:     1, // 1 second gap
+		Gap:     30,
 	}
-	engine := NewEngine([]models.Profile{profile}, mockMQTT, "test/review")
-
-	now := float64(time.Now().Unix())
+	clk := clock.NewVirtual(time.Unix(1000, 0))
+	engine := NewEngine([]models.Profile{profile}, mockMQTT, WithClock(clk))
 
 	// Start event
 	evt := models.FrigateEvent{
@@ -303,27 +408,27 @@ func TestEngine_GapLogic(t *testing.T) {
 			ID:        "evt1",
 			Camera:    "cam1",
 			Label:     "person",
-			StartTime: now,
+			StartTime: 1000,
 		},
 	}
 	engine.handleEvent(evt)
 	mockMQTT.Clear()
 
 	// End event
-	evt.After.EndTime = now // Ends "now"
+	clk.Set(time.Unix(1010, 0))
+	evt.After.EndTime = 1010
 	engine.handleEvent(evt)
 	mockMQTT.Clear() // Clear "update" from end event
 
-	// Tick immediately - Should NOT close because time.Since(now) < 1s
+	// Exactly at the gap boundary - Should NOT close
+	clk.Set(time.Unix(1040, 0))
 	engine.handleTick()
 	if len(mockMQTT.PublishedMessages) > 0 {
 		t.Errorf("Review closed too early! Messages: %v", mockMQTT.PublishedMessages)
 	}
 
-	// Wait 1.1s
-	time.Sleep(1100 * time.Millisecond)
-
-	// Tick - Should close
+	// One tick later - Should close
+	clk.Advance(time.Second)
 	engine.handleTick()
 
 	if len(mockMQTT.PublishedMessages) == 0 {
@@ -335,7 +440,7 @@ func TestEngine_GapLogic(t *testing.T) {
 }
 
 func TestGhostEvents(t *testing.T) {
-	mockMQTT := &MockMQTTPublisher{}
+	mockMQTT := &MockPublisher{}
 	profile := models.Profile{
 		Name:    "test_ghost",
 		Cameras: []string{"cam1"},
@@ -343,8 +448,8 @@ func TestGhostEvents(t *testing.T) {
 		Gap:     1,
 	}
 
-	engine := NewEngine([]models.Profile{profile}, mockMQTT, "test/review", WithPublishUpdates(true))
-	engine.ghostTimeout = 10 * time.Millisecond
+	clk := clock.NewVirtual(time.Unix(1000, 0))
+	engine := NewEngine([]models.Profile{profile}, mockMQTT, WithPublishUpdates(true), WithGhostTimeout(300), WithClock(clk))
 
 	// Start event
 	evt := models.FrigateEvent{
@@ -352,7 +457,7 @@ func TestGhostEvents(t *testing.T) {
 			ID:        "ghost_evt",
 			Camera:    "cam1",
 			Label:     "person",
-			StartTime: float64(time.Now().Unix()),
+			StartTime: 1000,
 		},
 	}
 	engine.handleEvent(evt)
@@ -365,10 +470,15 @@ func TestGhostEvents(t *testing.T) {
 		t.Fatal("Event should be active")
 	}
 
-	// Wait for ghost timeout
-	time.Sleep(20 * time.Millisecond)
+	// Exactly at the ghost timeout - still considered alive
+	clk.Advance(300 * time.Second)
+	engine.handleTick()
+	if len(mockMQTT.PublishedMessages) > 0 {
+		t.Fatalf("Ghost detected too early: %v", mockMQTT.PublishedMessages)
+	}
 
 	// Tick - Should detect ghost and close event
+	clk.Advance(time.Second)
 	engine.handleTick()
 
 	// Expect an update message (ghost cleanup)
@@ -380,8 +490,788 @@ func TestGhostEvents(t *testing.T) {
 		t.Errorf("Expected 'update', got %s", lastMsg.Type)
 	}
 
-	// Verify event logic closed
-	if tracked.Event.After.EndTime == 0 {
-		t.Error("Ghost event was not closed (EndTime is still 0)")
+	// Verify event logic closed at the time it was detected
+	if tracked.Event.After.EndTime != 1301 {
+		t.Errorf("Ghost event EndTime = %v, want 1301", tracked.Event.After.EndTime)
+	}
+}
+
+func TestEscalation(t *testing.T) {
+	mockMQTT := &MockPublisher{}
+	profile := models.Profile{
+		Name:          "test_escalate",
+		Cameras:       []string{"cam1"},
+		Gap:           30,
+		EscalateAfter: 60,
+	}
+	clk := clock.NewVirtual(time.Unix(1000, 0))
+	engine := NewEngine([]models.Profile{profile}, mockMQTT, WithClock(clk))
+
+	evt := models.FrigateEvent{
+		After: models.FrigateEventState{
+			ID:        "evt1",
+			Camera:    "cam1",
+			StartTime: 1000,
+		},
+	}
+	engine.handleEvent(evt)
+	mockMQTT.Clear()
+
+	clk.Set(time.Unix(1059, 0))
+	engine.handleTick()
+	if len(mockMQTT.PublishedMessages) > 0 {
+		t.Fatalf("Review escalated too early: %v", mockMQTT.PublishedMessages)
+	}
+
+	clk.Set(time.Unix(1060, 0))
+	engine.handleTick()
+	clk.Advance(time.Second)
+	engine.handleTick()
+
+	if len(mockMQTT.PublishedMessages) != 1 {
+		t.Fatalf("Expected exactly 1 escalate message, got %d", len(mockMQTT.PublishedMessages))
+	}
+	if mockMQTT.LastMessage().Type != models.MessageEscalate {
+		t.Errorf("Expected 'escalate', got %s", mockMQTT.LastMessage().Type)
+	}
+}
+
+// chanPublisher hands messages to the test goroutine while Run is active
+type chanPublisher chan models.MessagePayload
+
+func (c chanPublisher) Publish(msg models.MessagePayload) {
+	c <- msg
+}
+
+func TestEngine_RunTicksOnClock(t *testing.T) {
+	out := make(chanPublisher, 10)
+	profile := models.Profile{Name: "run", Cameras: []string{"cam1"}, Gap: 5}
+	clk := clock.NewVirtual(time.Unix(1000, 0))
+	engine := NewEngine([]models.Profile{profile}, out, WithClock(clk))
+	go engine.Run()
+
+	engine.IngestChannel() <- models.FrigateEvent{
+		After: models.FrigateEventState{ID: "evt1", Camera: "cam1", StartTime: 990, EndTime: 995},
+	}
+	if msg := <-out; msg.Type != models.MessageNew {
+		t.Fatalf("Expected 'new', got %s", msg.Type)
+	}
+
+	// Each Advance fires the engine's ticker; the review ended at 995 with a
+	// 5s gap, so it closes on the tick at 1001
+	clk.Advance(time.Second)
+
+	select {
+	case msg := <-out:
+		if msg.Type != models.MessageEnd {
+			t.Errorf("Expected 'end', got %s", msg.Type)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Engine did not tick on the virtual clock")
+	}
+}
+
+func TestEngine_ActiveEventsFromRunLoop(t *testing.T) {
+	out := make(chanPublisher, 10)
+	profiles := []models.Profile{
+		{Name: "all", Cameras: []string{"cam1"}, Gap: 30},
+		{Name: "people", Labels: []string{"person"}, Gap: 30},
+	}
+	clk := clock.NewVirtual(time.Unix(1000, 0))
+	engine := NewEngine(profiles, out, WithClock(clk))
+	go engine.Run()
+
+	engine.IngestChannel() <- models.FrigateEvent{Type: "end", After: models.FrigateEventState{ID: "b", Camera: "cam1", Label: "car", StartTime: 990, EndTime: 995}}
+	engine.IngestChannel() <- models.FrigateEvent{Type: "new", After: models.FrigateEventState{ID: "a", Camera: "cam1", Label: "person", StartTime: 1000}}
+
+	// 'new' for "all" (from b), then 'new' for "people" (from a)
+	<-out
+	<-out
+
+	active := engine.ActiveEvents()
+	if len(active) != 1 || active[0].ID != "a" {
+		t.Errorf("Expected only event a (once, despite two profiles), got %+v", active)
+	}
+}
+
+func TestRecover_SkipsProfilesWhoseGapRanOut(t *testing.T) {
+	out := make(chanPublisher, 10)
+	profiles := []models.Profile{
+		{Name: "long", Cameras: []string{"cam1"}, Gap: 600},
+		{Name: "short", Cameras: []string{"cam1"}, Gap: 10},
+	}
+	clk := clock.NewVirtual(time.Unix(1300, 0))
+	engine := NewEngine(profiles, out, WithClock(clk))
+	go engine.Run()
+
+	// Ended 300s before the restart: only "long" was still waiting out its gap
+	engine.Recover([]models.FrigateEvent{
+		{Type: "end", After: models.FrigateEventState{ID: "a", Camera: "cam1", Label: "person", StartTime: 990, EndTime: 1000}},
+	})
+
+	// Recover returns once the events are processed, so their messages are queued
+	var got []string
+	for len(out) > 0 {
+		msg := <-out
+		got = append(got, msg.Type+" "+msg.After.ProfileName)
+	}
+	if strings.Join(got, ",") != "new long" {
+		t.Errorf("Expected only the long profile's review, got %v", got)
+	}
+}
+
+func TestAuditDecisions(t *testing.T) {
+	mockMQTT := &MockPublisher{}
+	auditor := &MockAuditor{}
+	profiles := []models.Profile{
+		{Name: "front", Cameras: []string{"cam1"}, Gap: 0},
+		{Name: "zoned", Cameras: []string{"cam1"}, RequiredZones: []string{"porch"}},
+	}
+	engine := NewEngine(profiles, mockMQTT, WithAuditor(auditor))
+
+	evt := models.FrigateEvent{
+		After: models.FrigateEventState{
+			ID:           "evt1",
+			Camera:       "cam1",
+			Label:        "person",
+			StartTime:    1000,
+			EnteredZones: []string{"driveway"},
+		},
+	}
+	engine.handleEvent(evt)
+
+	if got := len(auditor.Kind(audit.KindEvent)); got != 1 {
+		t.Errorf("Expected 1 event record, got %d", got)
+	}
+
+	matches := auditor.Kind(audit.KindMatch)
+	if len(matches) != 1 || matches[0].Profile != "front" {
+		t.Errorf("Expected match for 'front', got %+v", matches)
+	}
+
+	rejects := auditor.Kind(audit.KindReject)
+	if len(rejects) != 1 || rejects[0].Profile != "zoned" || rejects[0].Criterion != criterionZone {
+		t.Errorf("Expected zone reject for 'zoned', got %+v", rejects)
+	}
+
+	evt.After.EndTime = 1001
+	engine.handleEvent(evt)
+	engine.handleTick()
+
+	var states []string
+	for _, rec := range auditor.Kind(audit.KindTransition) {
+		states = append(states, rec.To)
+	}
+	want := []string{"active", "pending-close", "ended"}
+	if !slices.Equal(states, want) {
+		t.Errorf("Transitions = %v, want %v", states, want)
+	}
+}
+
+func TestIngest_TypesStaleAndDuplicates(t *testing.T) {
+	mockMQTT := &MockPublisher{}
+	profile := models.Profile{Name: "front", Cameras: []string{"cam1"}, Gap: 30}
+	clk := clock.NewVirtual(time.Unix(1000, 0))
+	engine := NewEngine([]models.Profile{profile}, mockMQTT, WithPublishUpdates(true), WithClock(clk))
+
+	state := models.FrigateEventState{ID: "evt1", Camera: "cam1", StartTime: 1000, FrameTime: 1000}
+	engine.handleEvent(models.FrigateEvent{Type: "new", After: state})
+
+	// Exact repeat of the previous message
+	engine.handleEvent(models.FrigateEvent{Type: "new", After: state})
+
+	// Unknown message type
+	engine.handleEvent(models.FrigateEvent{Type: "snapshot", After: state})
+
+	// Newer update
+	newer := state
+	newer.FrameTime = 1005
+	newer.EnteredZones = []string{"porch"}
+	engine.handleEvent(models.FrigateEvent{Type: "update", After: newer})
+
+	// Delayed older update arriving out of order
+	engine.handleEvent(models.FrigateEvent{Type: "update", After: state})
+
+	if len(mockMQTT.PublishedMessages) != 2 {
+		t.Fatalf("Expected 2 messages (new, update), got %d", len(mockMQTT.PublishedMessages))
+	}
+
+	// End without an end_time uses the frame time
+	ended := newer
+	ended.FrameTime = 1010
+	engine.handleEvent(models.FrigateEvent{Type: "end", After: ended})
+
+	tracked := engine.activeReviews["front"].Events["evt1"]
+	if tracked.Event.After.EndTime != 1010 {
+		t.Fatalf("Expected end message to close the event at 1010, got %v", tracked.Event.After.EndTime)
+	}
+
+	// A late update without an end time must not reopen the ended event
+	late := newer
+	late.FrameTime = 1011
+	engine.handleEvent(models.FrigateEvent{Type: "update", After: late})
+	if tracked := engine.activeReviews["front"].Events["evt1"]; tracked.Event.After.EndTime == 0 {
+		t.Error("Stale update reopened an ended event")
+	}
+
+	want := Stats{Accepted: 3, UnknownType: 1, Stale: 2, Duplicate: 1}
+	if got := engine.Stats(); got != want {
+		t.Errorf("Stats = %+v, want %+v", got, want)
+	}
+}
+
+func TestReevaluation_RemovesEventsThatStopMatching(t *testing.T) {
+	mockMQTT := &MockPublisher{}
+	profile := models.Profile{Name: "people", Labels: []string{"person"}, Gap: 30}
+	clk := clock.NewVirtual(time.Unix(1000, 0))
+	engine := NewEngine([]models.Profile{profile}, mockMQTT, WithPublishUpdates(true), WithClock(clk))
+
+	a := models.FrigateEventState{ID: "a", Camera: "cam1", Label: "person", StartTime: 1000}
+	b := models.FrigateEventState{ID: "b", Camera: "cam1", Label: "person", StartTime: 1002}
+	engine.handleEvent(models.FrigateEvent{Type: "new", After: a})
+	engine.handleEvent(models.FrigateEvent{Type: "new", After: b})
+
+	// Frigate relabels b as a dog
+	b.Label = "dog"
+	engine.handleEvent(models.FrigateEvent{Type: "update", After: b})
+
+	msg := mockMQTT.LastMessage()
+	if msg.Type != models.MessageUpdate || msg.After.EventCount != 1 {
+		t.Fatalf("Expected update with 1 event, got %s with %d", msg.Type, msg.After.EventCount)
+	}
+	if len(msg.RemovedEvents) != 1 || msg.RemovedEvents[0].ID != "b" || msg.RemovedEvents[0].Reason != criterionLabel {
+		t.Errorf("Unexpected removed events: %+v", msg.RemovedEvents)
+	}
+
+	// a turns out to be a false positive, leaving the published review empty
+	clk.Set(time.Unix(1010, 0))
+	a.FalsePositive = true
+	engine.handleEvent(models.FrigateEvent{Type: "update", After: a})
+
+	msg = mockMQTT.LastMessage()
+	if msg.Type != models.MessageEnd || msg.After.State != "ended" || msg.After.EventCount != 0 {
+		t.Fatalf("Expected empty review to end, got %s (%s, %d events)", msg.Type, msg.After.State, msg.After.EventCount)
+	}
+	if msg.After.StartTime != 1000 || msg.After.EndTime == nil || *msg.After.EndTime != 1010 {
+		t.Errorf("Unexpected times on ended review: start %v end %v", msg.After.StartTime, msg.After.EndTime)
+	}
+	if engine.ActiveReviews() != 0 {
+		t.Errorf("Expected no active reviews, got %d", engine.ActiveReviews())
+	}
+}
+
+func TestReevaluation_DiscardsUnpublishedReview(t *testing.T) {
+	mockMQTT := &MockPublisher{}
+	profile := models.Profile{Name: "people", Labels: []string{"person"}}
+	engine := NewEngine([]models.Profile{profile}, mockMQTT)
+
+	// Simulate a review that was created but never published
+	review := &ReviewInstance{ID: "r1", Profile: profile, State: "active", Events: map[string]*TrackedEvent{}}
+	evt := models.FrigateEvent{After: models.FrigateEventState{ID: "a", Label: "person"}}
+	review.Events["a"] = &TrackedEvent{Event: &evt}
+	engine.activeReviews["people"] = review
+
+	engine.handleEvent(models.FrigateEvent{Type: "update", After: models.FrigateEventState{ID: "a", Label: "cat"}})
+
+	if len(mockMQTT.PublishedMessages) != 0 {
+		t.Errorf("Expected unpublished review to be discarded silently, got %v", mockMQTT.PublishedMessages)
+	}
+	if engine.ActiveReviews() != 0 {
+		t.Errorf("Expected review to be discarded")
+	}
+}
+
+func TestUpdates_SkipUnchangedSummary(t *testing.T) {
+	mockMQTT := &MockPublisher{}
+	profile := models.Profile{Name: "people", Labels: []string{"person"}, Gap: 30}
+	clk := clock.NewVirtual(time.Unix(1000, 0))
+	engine := NewEngine([]models.Profile{profile}, mockMQTT, WithPublishUpdates(true), WithClock(clk))
+
+	a := models.FrigateEventState{ID: "a", Camera: "cam1", Label: "person", StartTime: 1000, FrameTime: 1000}
+	engine.handleEvent(models.FrigateEvent{Type: "new", After: a})
+
+	// A newer frame with the same cameras, zones and objects is not worth an update
+	a.FrameTime = 1001
+	engine.handleEvent(models.FrigateEvent{Type: "update", After: a})
+	if len(mockMQTT.PublishedMessages) != 1 {
+		t.Fatalf("Expected only the 'new' message, got %d messages", len(mockMQTT.PublishedMessages))
+	}
+
+	a.FrameTime = 1002
+	a.EnteredZones = []string{"porch"}
+	engine.handleEvent(models.FrigateEvent{Type: "update", After: a})
+	msg := mockMQTT.LastMessage()
+	if msg.Type != models.MessageUpdate || !slices.Equal(msg.After.Zones, []string{"porch"}) {
+		t.Fatalf("Expected update with the new zone, got %s %v", msg.Type, msg.After.Zones)
+	}
+	if msg.Before == nil || len(msg.Before.Zones) != 0 {
+		t.Errorf("Expected 'before' to be the previously published state, got %+v", msg.Before)
+	}
+	if !slices.Equal(msg.Changes, []string{models.ChangeNewZone}) {
+		t.Errorf("Expected changes [new_zone], got %v", msg.Changes)
+	}
+}
+
+func TestDiffReviewStates(t *testing.T) {
+	base := models.ReviewState{
+		EventCount:   2,
+		ActiveEvents: 2,
+		LinkedEvents: []models.LinkedEventSummary{{ID: "a", Camera: "cam1", Active: true}, {ID: "b", Camera: "cam1", Active: true}},
+		Objects:      []string{"person"},
+		Cameras:      []string{"cam1"},
+		Zones:        []string{"porch"},
+	}
+
+	tests := []struct {
+		name   string
+		before *models.ReviewState
+		after  func(s models.ReviewState) models.ReviewState
+		want   []string
+	}{
+		{
+			name:   "Unpublished",
+			before: nil,
+			after:  func(s models.ReviewState) models.ReviewState { return s },
+			want:   []string{models.ChangeAddedEvent, models.ChangeNewCamera, models.ChangeNewZone, models.ChangeNewObject},
+		},
+		{
+			name:   "No-op",
+			before: &base,
+			after: func(s models.ReviewState) models.ReviewState {
+				s.StartTime = 1234
+				return s
+			},
+			want: nil,
+		},
+		{
+			name:   "Event Ended",
+			before: &base,
+			after: func(s models.ReviewState) models.ReviewState {
+				s.LinkedEvents = []models.LinkedEventSummary{{ID: "a", Camera: "cam1", Active: false}, {ID: "b", Camera: "cam1", Active: true}}
+				return s
+			},
+			want: []string{models.ChangeEventEnded},
+		},
+		{
+			name:   "Added On New Camera And Removed",
+			before: &base,
+			after: func(s models.ReviewState) models.ReviewState {
+				s.LinkedEvents = []models.LinkedEventSummary{{ID: "a", Camera: "cam1", Active: true}, {ID: "c", Camera: "cam2", Active: true}}
+				s.Cameras = []string{"cam1", "cam2"}
+				s.Objects = []string{"car", "person"}
+				return s
+			},
+			want: []string{models.ChangeAddedEvent, models.ChangeRemovedEvent, models.ChangeNewCamera, models.ChangeNewObject},
+		},
+		{
+			name:   "Severity Raised",
+			before: &base,
+			after: func(s models.ReviewState) models.ReviewState {
+				s.Severity = models.SeverityAlert
+				return s
+			},
+			want: []string{models.ChangeSeverity},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			after := tt.after(base)
+			if got := diffReviewStates(tt.before, &after); !slices.Equal(got, tt.want) {
+				t.Errorf("diffReviewStates() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdates_ThrottledByProfileInterval(t *testing.T) {
+	mockMQTT := &MockPublisher{}
+	profile := models.Profile{Name: "people", Labels: []string{"person"}, Gap: 30, UpdateInterval: 500}
+	clk := clock.NewVirtual(time.Unix(1000, 0))
+	engine := NewEngine([]models.Profile{profile}, mockMQTT, WithPublishUpdates(true), WithClock(clk))
+
+	if engine.TickInterval() != 500*time.Millisecond {
+		t.Errorf("Expected tick interval to follow the update interval, got %v", engine.TickInterval())
+	}
+
+	engine.handleEvent(models.FrigateEvent{Type: "new", After: models.FrigateEventState{ID: "a", Camera: "cam1", Label: "person", StartTime: 1000}})
+
+	// Two events join within the window and are merged into one update
+	clk.Advance(100 * time.Millisecond)
+	engine.handleEvent(models.FrigateEvent{Type: "new", After: models.FrigateEventState{ID: "b", Camera: "cam2", Label: "person", StartTime: 1000}})
+	clk.Advance(100 * time.Millisecond)
+	engine.handleEvent(models.FrigateEvent{Type: "new", After: models.FrigateEventState{ID: "c", Camera: "cam3", Label: "person", StartTime: 1000}})
+	engine.handleTick()
+	if len(mockMQTT.PublishedMessages) != 1 {
+		t.Fatalf("Expected updates to be held back, got %d messages", len(mockMQTT.PublishedMessages))
+	}
+
+	clk.Advance(300 * time.Millisecond)
+	engine.handleTick()
+	if len(mockMQTT.PublishedMessages) != 2 {
+		t.Fatalf("Expected one merged update, got %d messages", len(mockMQTT.PublishedMessages))
+	}
+	msg := mockMQTT.LastMessage()
+	if msg.Type != models.MessageUpdate || msg.Before.EventCount != 1 || msg.After.EventCount != 3 {
+		t.Errorf("Unexpected merged update: %s %d => %d", msg.Type, msg.Before.EventCount, msg.After.EventCount)
+	}
+
+	engine.handleTick()
+	if len(mockMQTT.PublishedMessages) != 2 {
+		t.Errorf("Expected no further updates, got %d messages", len(mockMQTT.PublishedMessages))
+	}
+
+	if got := tickIntervalFor([]models.Profile{{UpdateInterval: 20}}); got != minTickInterval {
+		t.Errorf("Expected tick interval floor %v, got %v", minTickInterval, got)
+	}
+}
+
+func TestSubLabelUnmarshal(t *testing.T) {
+	tests := map[string]string{
+		`{"sub_label": "alice"}`:       "alice",
+		`{"sub_label": ["bob", 0.87]}`: "bob",
+		`{"sub_label": null}`:          "",
+		`{"label": "person"}`:          "",
+	}
+	for payload, want := range tests {
+		var state models.FrigateEventState
+		if err := json.Unmarshal([]byte(payload), &state); err != nil {
+			t.Errorf("%s: %v", payload, err)
+			continue
+		}
+		if string(state.SubLabel) != want {
+			t.Errorf("%s: sub label = %q, want %q", payload, state.SubLabel, want)
+		}
+	}
+}
+
+func TestEngine_SourceQualifiedCameras(t *testing.T) {
+	pub := &MockPublisher{}
+	profiles := []models.Profile{
+		{Name: "any_driveway", Cameras: []string{"driveway"}, Gap: 30},
+		{Name: "garage_driveway", Cameras: []string{"garage/driveway"}, Gap: 30},
+	}
+	e := NewEngine(profiles, pub, WithPublishUpdates(true), WithClock(clock.NewVirtual(time.Unix(1000, 0))))
+
+	e.handleEvent(models.FrigateEvent{Type: "new", After: models.FrigateEventState{
+		ID: "house-1", Source: "house", Camera: "driveway", Label: "car", StartTime: 1000,
+	}})
+	e.handleEvent(models.FrigateEvent{Type: "new", After: models.FrigateEventState{
+		ID: "garage-1", Source: "garage", Camera: "driveway", Label: "car", StartTime: 1001,
+	}})
+
+	cameras := make(map[string][]string)
+	for _, msg := range pub.PublishedMessages {
+		cameras[msg.After.ProfileName] = msg.After.Cameras
+	}
+	if got := cameras["any_driveway"]; !slices.Equal(got, []string{"garage/driveway", "house/driveway"}) {
+		t.Errorf("any_driveway cameras = %v, want both sources", got)
+	}
+	if got := cameras["garage_driveway"]; !slices.Equal(got, []string{"garage/driveway"}) {
+		t.Errorf("garage_driveway cameras = %v, want the garage source only", got)
+	}
+}
+
+func TestEngine_MergesReviewSegments(t *testing.T) {
+	pub := &MockPublisher{}
+	profile := models.Profile{Name: "yard", Input: models.InputReviews, Gap: 30}
+	e := NewEngine([]models.Profile{profile}, pub, WithPublishUpdates(true), WithClock(clock.NewVirtual(time.Unix(1000, 0))))
+
+	var first, second models.FrigateReview
+	if err := json.Unmarshal([]byte(`{"type": "new", "after": {"id": "seg1", "camera": "yard", "start_time": 1000, "end_time": null,
+		"severity": "detection", "data": {"detections": ["ev1"], "objects": ["dog"], "sub_labels": [], "zones": [], "audio": []}}}`), &first); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if err := json.Unmarshal([]byte(`{"type": "new", "after": {"id": "seg2", "camera": "gate", "start_time": 1005, "end_time": null,
+		"severity": "alert", "data": {"detections": ["ev2"], "objects": ["person"], "sub_labels": [], "zones": ["path"], "audio": ["speech"]}}}`), &second); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	e.handleEvent(first.Event())
+	e.handleEvent(second.Event())
+
+	if len(pub.PublishedMessages) != 2 {
+		t.Fatalf("Expected new and update, got %d messages", len(pub.PublishedMessages))
+	}
+	msg := pub.LastMessage()
+	if msg.After.Severity != models.SeverityAlert {
+		t.Errorf("Severity = %q, want the highest of the segments", msg.After.Severity)
+	}
+	if !slices.Equal(msg.After.Objects, []string{"dog", "person", "speech"}) {
+		t.Errorf("Objects = %v, want every segment label", msg.After.Objects)
+	}
+	if !slices.Contains(msg.Changes, models.ChangeSeverity) {
+		t.Errorf("Changes = %v, want %s", msg.Changes, models.ChangeSeverity)
+	}
+}
+
+func TestEngine_PointInTimeEvents(t *testing.T) {
+	pub := &MockPublisher{}
+	profile := models.Profile{
+		Name:          "break_in",
+		Labels:        []string{"glass_break", "person"},
+		CustomLabels:  []string{"door_forced"},
+		RequiredZones: []string{"porch"},
+		Gap:           30,
+		PointDuration: 5,
+	}
+	clk := clock.NewVirtual(time.Unix(1000, 0))
+	e := NewEngine([]models.Profile{profile}, pub, WithPublishUpdates(true), WithClock(clk))
+
+	// Audio has no zones, yet passes the zone requirement and ends after 5s
+	e.handleEvent(models.FrigateEvent{Type: "new", After: models.FrigateEventState{
+		ID: "audio1", Camera: "porch_cam", Label: "glass_break", StartTime: 1000,
+		Data: &models.EventData{Type: models.EventTypeAudio},
+	}})
+	if msg := pub.LastMessage(); msg == nil || msg.Type != models.MessageNew || msg.After.ActiveEvents != 0 {
+		t.Fatalf("Expected a 'new' with the audio event already ended, got %+v", msg)
+	}
+
+	// A person 20s later is within the gap after the point duration
+	clk.Set(time.Unix(1020, 0))
+	e.handleTick()
+	e.handleEvent(models.FrigateEvent{Type: "new", After: models.FrigateEventState{
+		ID: "obj1", Camera: "porch_cam", Label: "person", StartTime: 1020, EnteredZones: []string{"porch"},
+	}})
+	e.handleEvent(models.FrigateEvent{Type: "new", After: models.FrigateEventState{
+		ID: "ext1", Camera: "porch_cam", Label: "door_forced", StartTime: 1021,
+		Data: &models.EventData{Type: models.EventTypeExternal},
+	}})
+
+	msg := pub.LastMessage()
+	if msg.Type != models.MessageUpdate || msg.After.EventCount != 3 {
+		t.Fatalf("Expected all events in one review, got %s with %d events", msg.Type, msg.After.EventCount)
+	}
+	if !slices.Equal(msg.After.Objects, []string{"door_forced", "glass_break", "person"}) {
+		t.Errorf("Objects = %v", msg.After.Objects)
+	}
+	if len(e.activeReviews) != 1 {
+		t.Errorf("Expected 1 active review, got %d", len(e.activeReviews))
+	}
+}
+
+func TestEngine_PointInTimeEventsNeedListedLabelsWithZones(t *testing.T) {
+	pub := &MockPublisher{}
+	profile := models.Profile{Name: "driveway", Cameras: []string{"cam1"}, RequiredZones: []string{"driveway"}, Gap: 30, PointDuration: 5}
+	e := NewEngine([]models.Profile{profile}, pub, WithClock(clock.NewVirtual(time.Unix(1000, 0))))
+
+	// Without labels, the zone requirement still rejects audio
+	e.handleEvent(models.FrigateEvent{Type: "new", After: models.FrigateEventState{
+		ID: "audio1", Camera: "cam1", Label: "speech", StartTime: 1000,
+		Data: &models.EventData{Type: models.EventTypeAudio},
+	}})
+	if len(pub.PublishedMessages) != 0 {
+		t.Fatalf("Expected audio outside any zone to be rejected, got %+v", pub.LastMessage())
+	}
+
+	e.handleEvent(models.FrigateEvent{Type: "new", After: models.FrigateEventState{
+		ID: "obj1", Camera: "cam1", Label: "car", StartTime: 1000, EnteredZones: []string{"driveway"},
+	}})
+	if msg := pub.LastMessage(); msg == nil || msg.Type != models.MessageNew {
+		t.Fatalf("Expected a 'new' for the car in the driveway, got %+v", msg)
+	}
+}
+
+func TestDwell_QualifiesAfterMinDwellAndFlagsLoitering(t *testing.T) {
+	pub := &MockPublisher{}
+	profile := models.Profile{
+		Name:            "door",
+		RequiredZones:   []string{"door"},
+		MinDwell:        10,
+		LoiterThreshold: 30,
+		Gap:             30,
+	}
+	clk := clock.NewVirtual(time.Unix(1000, 0))
+	e := NewEngine([]models.Profile{profile}, pub, WithPublishUpdates(true), WithClock(clk))
+
+	update := func(at float64, zones ...string) {
+		e.handleEvent(models.FrigateEvent{Type: "update", After: models.FrigateEventState{
+			ID: "p1", Camera: "porch", Label: "person", StartTime: 1000, FrameTime: at,
+			CurrentZones: zones, EnteredZones: []string{"door"},
+		}})
+	}
+
+	// Passing through the zone is not enough
+	update(1000, "door")
+	update(1004)
+	clk.Set(time.Unix(1008, 0))
+	update(1008, "door")
+	if len(pub.PublishedMessages) != 0 {
+		t.Fatalf("Expected no review before min_dwell, got %d messages", len(pub.PublishedMessages))
+	}
+
+	// A stationary object sends no updates, the tick notices the dwell
+	clk.Set(time.Unix(1019, 0))
+	e.handleTick()
+	if msg := pub.LastMessage(); msg == nil || msg.Type != models.MessageNew || msg.After.Loitering {
+		t.Fatalf("Expected a 'new' without loitering once dwelling 11s, got %+v", msg)
+	}
+
+	clk.Set(time.Unix(1040, 0))
+	e.handleTick()
+	msg := pub.LastMessage()
+	if msg.Type != models.MessageEscalate || !msg.After.Loitering {
+		t.Fatalf("Expected a loitering 'escalate' after 32s, got %s (loitering %v)", msg.Type, msg.After.Loitering)
+	}
+	if !slices.Contains(msg.Changes, models.ChangeLoitering) {
+		t.Errorf("Changes = %v, want %s", msg.Changes, models.ChangeLoitering)
+	}
+}
+
+func TestZoneDwell(t *testing.T) {
+	var d zoneDwell
+	d.observe(models.FrigateEventState{FrameTime: 100, CurrentZones: []string{"a"}}, time.Time{})
+	d.observe(models.FrigateEventState{FrameTime: 110, CurrentZones: []string{"a", "b"}}, time.Time{})
+	d.observe(models.FrigateEventState{FrameTime: 125, CurrentZones: []string{"b"}}, time.Time{})
+
+	if got := d.dwell([]string{"a"}, 200); got != 25 {
+		t.Errorf("dwell(a) = %v, want the finished 25s stay", got)
+	}
+	if got := d.dwell([]string{"b"}, 130); got != 20 {
+		t.Errorf("dwell(b) = %v, want the ongoing 20s stay", got)
+	}
+
+	d.observe(models.FrigateEventState{FrameTime: 140, EndTime: 140, CurrentZones: []string{"b"}}, time.Time{})
+	if got := d.dwell(nil, 500); got != 30 {
+		t.Errorf("dwell(any) = %v, want 30s, stays end with the event", got)
+	}
+}
+
+func TestZoneMode_SinceReview(t *testing.T) {
+	pub := &MockPublisher{}
+	profile := models.Profile{Name: "porch", RequiredZones: []string{"porch"}, ZoneMode: models.ZoneModeSinceReview, Gap: 30}
+	clk := clock.NewVirtual(time.Unix(1000, 0))
+	e := NewEngine([]models.Profile{profile}, pub, WithPublishUpdates(true), WithClock(clk))
+
+	update := func(id string, at float64, current []string, entered ...string) {
+		clk.Set(time.Unix(int64(at), 0))
+		e.handleEvent(models.FrigateEvent{Type: "update", After: models.FrigateEventState{
+			ID: id, Camera: "cam1", Label: "person", StartTime: 990, FrameTime: at,
+			CurrentZones: current, EnteredZones: entered,
+		}})
+	}
+
+	// b visited the porch before the review opened, a opens it
+	update("b", 995, []string{"porch"}, "porch")
+	update("b", 998, []string{"yard"}, "porch", "yard")
+	e.activeReviews = make(map[string]*ReviewInstance) // Forget b's review, as if it had closed
+	pub.Clear()
+
+	update("a", 1000, []string{"porch"}, "porch")
+	update("b", 1002, []string{"yard"}, "porch", "yard")
+	if msg := pub.LastMessage(); msg.After.EventCount != 1 {
+		t.Fatalf("Expected only a in the review, got %d events", msg.After.EventCount)
+	}
+
+	// Coming back to the porch after the review opened counts
+	update("b", 1005, []string{"porch"}, "porch", "yard")
+	msg := pub.LastMessage()
+	if msg.After.EventCount != 2 {
+		t.Fatalf("Expected b to join after re-entering, got %d events", msg.After.EventCount)
+	}
+	if !slices.Equal(msg.After.Zones, []string{"porch", "yard"}) || !slices.Equal(msg.After.CurrentZones, []string{"porch"}) {
+		t.Errorf("Zones = %v, CurrentZones = %v", msg.After.Zones, msg.After.CurrentZones)
+	}
+
+	// Leaving the porch keeps both events, only the occupied zones change
+	update("a", 1006, []string{"yard"}, "porch", "yard")
+	update("b", 1007, nil, "porch", "yard")
+	msg = pub.LastMessage()
+	if msg.After.EventCount != 2 || !slices.Equal(msg.After.CurrentZones, []string{"yard"}) {
+		t.Errorf("Expected both events with only yard occupied, got %d events, %v", msg.After.EventCount, msg.After.CurrentZones)
+	}
+	if !slices.Equal(msg.Changes, []string{models.ChangeCurrentZones}) {
+		t.Errorf("Changes = %v, want only %s", msg.Changes, models.ChangeCurrentZones)
+	}
+}
+
+func TestDwell_GhostedEventDoesNotReopenReviews(t *testing.T) {
+	pub := &MockPublisher{}
+	profile := models.Profile{Name: "porch", RequiredZones: []string{"porch"}, MinDwell: 5, Gap: 10}
+	clk := clock.NewVirtual(time.Unix(1000, 0))
+	e := NewEngine([]models.Profile{profile}, pub, WithGhostTimeout(30), WithClock(clk))
+
+	// One update, then the object is stuck: no further messages, no end
+	e.handleEvent(models.FrigateEvent{Type: "new", After: models.FrigateEventState{
+		ID: "stuck", Camera: "cam1", Label: "person", StartTime: 1000, FrameTime: 1000,
+		CurrentZones: []string{"porch"}, EnteredZones: []string{"porch"},
+	}})
+	for i := 0; i < 300; i++ {
+		clk.Advance(time.Second)
+		e.handleTick()
+	}
+
+	counts := make(map[string]int)
+	for _, msg := range pub.PublishedMessages {
+		counts[msg.Type]++
+	}
+	if counts[models.MessageNew] != 1 || counts[models.MessageEnd] != 1 {
+		t.Errorf("Expected one review, got %d 'new' and %d 'end' messages", counts[models.MessageNew], counts[models.MessageEnd])
+	}
+}
+
+func TestDwell_PointInTimeEventDoesNotReopenReviews(t *testing.T) {
+	pub := &MockPublisher{}
+	profiles := []models.Profile{
+		{Name: "door", Labels: []string{"bark"}, RequiredZones: []string{"porch"}, MinDwell: 5, Gap: 10, PointDuration: 5},
+	}
+	clk := clock.NewVirtual(time.Unix(1000, 0))
+	e := NewEngine(profiles, pub, WithClock(clk))
+
+	e.handleEvent(models.FrigateEvent{Type: "new", After: models.FrigateEventState{
+		ID: "bark", Camera: "cam1", Label: "bark", StartTime: 1000,
+		Data: &models.EventData{Type: models.EventTypeAudio},
+	}})
+	for i := 0; i < 120; i++ {
+		clk.Advance(time.Second)
+		e.handleTick()
+	}
+
+	if got := len(pub.PublishedMessages); got != 2 {
+		t.Errorf("Expected 'new' and 'end' once, got %d messages", got)
+	}
+}
+
+func TestZoneMode_CurrentKeepsEventsThatLeave(t *testing.T) {
+	pub := &MockPublisher{}
+	profile := models.Profile{Name: "porch", RequiredZones: []string{"porch"}, ZoneMode: models.ZoneModeCurrent, Gap: 10}
+	clk := clock.NewVirtual(time.Unix(1000, 0))
+	e := NewEngine([]models.Profile{profile}, pub, WithPublishUpdates(true), WithClock(clk))
+
+	send := func(msgType string, at float64, current []string, endTime float64) {
+		clk.Set(time.Unix(int64(at), 0))
+		e.handleEvent(models.FrigateEvent{Type: msgType, After: models.FrigateEventState{
+			ID: "a", Camera: "cam1", Label: "person", StartTime: 1000, FrameTime: at, EndTime: endTime,
+			CurrentZones: current, EnteredZones: []string{"porch", "yard"},
+		}})
+	}
+
+	// Walking through the yard alone doesn't qualify
+	send("new", 995, []string{"yard"}, 0)
+	if len(pub.PublishedMessages) != 0 {
+		t.Fatalf("Expected no review outside the porch, got %d messages", len(pub.PublishedMessages))
+	}
+
+	send("update", 1000, []string{"porch"}, 0)
+	send("update", 1004, []string{"yard"}, 0)
+	msg := pub.LastMessage()
+	if msg.Type != models.MessageUpdate || msg.After.EventCount != 1 || len(msg.RemovedEvents) > 0 {
+		t.Fatalf("Expected the event kept after leaving, got %s with %d events, removed %v", msg.Type, msg.After.EventCount, msg.RemovedEvents)
+	}
+	if msg.After.ActiveEvents != 0 || len(msg.After.CurrentZones) != 0 {
+		t.Errorf("Expected the event's part ended, got %d active, current zones %v", msg.After.ActiveEvents, msg.After.CurrentZones)
+	}
+
+	// Still in the yard later, then Frigate ends it: the gap runs from leaving the porch
+	send("update", 1008, []string{"yard"}, 0)
+	send("end", 1012, nil, 1012)
+	clk.Set(time.Unix(1015, 0))
+	e.handleTick()
+	msg = pub.LastMessage()
+	if msg.Type != models.MessageEnd || msg.After.EventCount != 1 {
+		t.Fatalf("Expected the review to end with its event, got %s with %d events", msg.Type, msg.After.EventCount)
+	}
+	if end := msg.After.EndTime; end == nil || *end != 1004 {
+		t.Errorf("Review end time = %v, want 1004 when it left the porch", end)
 	}
 }
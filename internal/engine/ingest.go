@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"reflect"
+	"time"
+
+	"frigate-custom-reviews/internal/audit"
+	"frigate-custom-reviews/internal/logger"
+	"frigate-custom-reviews/internal/models"
+)
+
+// Frigate event message types
+const (
+	frigateNew    = "new"
+	frigateUpdate = "update"
+	frigateEnd    = "end"
+)
+
+// Reasons an ingested message is ignored
+const (
+	ignoreUnknownType = "unknown_type"
+	ignoreStale       = "stale"
+	ignoreDuplicate   = "duplicate"
+)
+
+// minHistoryRetention is how long the last message of an event is kept for
+// stale and duplicate detection after it was last seen
+const minHistoryRetention = time.Hour
+
+// eventHistory is the last accepted message for a Frigate event ID
+type eventHistory struct {
+	last     models.FrigateEvent
+	lastSeen time.Time
+	dwell    zoneDwell
+	closed   bool // Ended by the engine (ghost timeout) although last is still active
+}
+
+// Stats counts how ingested messages were handled
+type Stats struct {
+	Accepted    int64
+	UnknownType int64
+	Stale       int64
+	Duplicate   int64
+}
+
+// Stats returns a snapshot of the ingest counters. Safe to call while Run is active.
+func (e *Engine) Stats() Stats {
+	return Stats{
+		Accepted:    e.stats.accepted.Load(),
+		UnknownType: e.stats.unknownType.Load(),
+		Stale:       e.stats.stale.Load(),
+		Duplicate:   e.stats.duplicate.Load(),
+	}
+}
+
+// admit normalizes evt according to its Frigate type and decides whether it
+// should be processed. Messages with an unknown type, messages older than
+// what we already have (by frame time, or non-end updates after an end) and
+// exact repeats are counted and ignored.
+func (e *Engine) admit(evt *models.FrigateEvent) bool {
+	state := &evt.After
+
+	switch evt.Type {
+	case frigateNew, frigateUpdate, "":
+		// An empty type comes from API recovery and older payloads; treat as update
+	case frigateEnd:
+		// Trust the type even if the payload lacks an end time
+		if state.EndTime == 0 {
+			state.EndTime = state.FrameTime
+			if state.EndTime == 0 {
+				state.EndTime = float64(e.now().Unix())
+			}
+		}
+	default:
+		e.stats.unknownType.Add(1)
+		e.ignore(evt, ignoreUnknownType)
+		return false
+	}
+
+	prev, seen := e.history[state.ID]
+	if seen {
+		last := prev.last.After
+
+		if last.EndTime != 0 && state.EndTime == 0 {
+			e.stats.stale.Add(1)
+			e.ignore(evt, ignoreStale)
+			return false
+		}
+
+		if state.FrameTime > 0 && state.FrameTime < last.FrameTime {
+			e.stats.stale.Add(1)
+			e.ignore(evt, ignoreStale)
+			return false
+		}
+
+		if reflect.DeepEqual(prev.last, *evt) {
+			e.stats.duplicate.Add(1)
+			e.ignore(evt, ignoreDuplicate)
+			return false
+		}
+	}
+
+	if !seen {
+		prev = &eventHistory{}
+		e.history[state.ID] = prev
+	}
+	prev.last = *evt
+	prev.lastSeen = e.now()
+	prev.closed = false
+	prev.dwell.observe(*state, e.now())
+	e.stats.accepted.Add(1)
+	return true
+}
+
+func (e *Engine) ignore(evt *models.FrigateEvent, reason string) {
+	logger.Debugf("Ignored Event: ID: %v, Type: %v, Reason: %v", evt.After.ID, evt.Type, reason)
+	e.record(audit.Record{Kind: audit.KindIgnored, EventID: evt.After.ID, Reason: reason})
+}
+
+// pruneHistory forgets events that haven't been seen for a while
+func (e *Engine) pruneHistory() {
+	retention := max(minHistoryRetention, 2*e.ghostTimeout)
+	for id, h := range e.history {
+		if e.now().Sub(h.lastSeen) > retention {
+			delete(e.history, id)
+		}
+	}
+}
@@ -1,8 +1,11 @@
 package engine
 
 import (
+	"sync/atomic"
 	"time"
 
+	"frigate-custom-reviews/internal/audit"
+	"frigate-custom-reviews/internal/clock"
 	"frigate-custom-reviews/internal/models"
 )
 
@@ -21,21 +24,46 @@ type ReviewInstance struct {
 	State        string // "active" or "ended"
 
 	// Internal tracking
+	OpenedAt       time.Time // When the review was created, for zone_mode since_review
 	LastUpdated    time.Time // Last time we touched this struct (wall clock)
 	SentFirstEvent bool      // Whether we've emitted the 'new' message yet
+	Escalated      bool      // Whether we've emitted the 'escalate' message yet
+	Loitering      bool      // Whether an object stayed past the profile's loiter_threshold
+
+	LastPublished *models.ReviewState // State carried by the last message, the next message's 'before'
+	LastPublishAt time.Time           // When the last message was sent
+	PendingUpdate bool                // An 'update' is being held back by the profile's update interval
 }
 
 type Engine struct {
 	profiles       []models.Profile
 	activeReviews  map[string]*ReviewInstance // Key is Profile Name
 	ingestChan     chan models.FrigateEvent
-	mqttClient     MQTTPublisher
-	publishTopic   string
+	control        chan func() // Run executes these between events and ticks
+	publisher      Publisher
+	auditor        Auditor
+	clock          clock.Clock
+	history        map[string]*eventHistory // Key is Event ID
+	stats          ingestStats
 	publishUpdates bool
 	ghostTimeout   time.Duration
+	tickInterval   time.Duration
+}
+
+// Publisher receives review lifecycle messages (see sink.Dispatcher).
+// Implementations must not block the engine loop.
+type Publisher interface {
+	Publish(msg models.MessagePayload)
+}
+
+// Auditor records ingested events and engine decisions (see audit.Log)
+type Auditor interface {
+	Record(rec audit.Record)
 }
 
-// MQTTPublisher interface to decouple engine from specific mqtt implementation
-type MQTTPublisher interface {
-	Publish(topic string, payload interface{}) error
+type ingestStats struct {
+	accepted    atomic.Int64
+	unknownType atomic.Int64
+	stale       atomic.Int64
+	duplicate   atomic.Int64
 }
@@ -1,80 +1,200 @@
 package frigate
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 
+	"frigate-custom-reviews/internal/logger"
 	"frigate-custom-reviews/internal/models"
+	"frigate-custom-reviews/internal/tlsconfig"
 )
 
+// ErrNotFound is returned for events (or other resources) Frigate doesn't know (anymore)
+var ErrNotFound = errors.New("not found")
+
+// loginCookie holds the JWT issued by /api/login
+const loginCookie = "frigate_token"
+
 type Client struct {
-	config models.FrigateConfig
-	client *http.Client
+	config  models.FrigateConfig
+	client  *http.Client
+	backoff time.Duration
+
+	mu    sync.Mutex
+	token string // JWT from /api/login, fetched on first use
 }
 
-func NewClient(cfg models.FrigateConfig) *Client {
+// NewClient returns a client for the Frigate HTTP API. It fails only if the
+// TLS settings cannot be loaded.
+func NewClient(cfg models.FrigateConfig) (*Client, error) {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 10
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 2
+	}
+	if cfg.RetryBackoffMs == 0 {
+		cfg.RetryBackoffMs = 500
+	}
+	cfg.URL = strings.TrimRight(cfg.URL, "/")
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	tlsConfig, err := tlsconfig.Build(cfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("frigate: %w", err)
+	}
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+
 	return &Client{
 		config: cfg,
 		client: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout:   time.Duration(cfg.Timeout) * time.Second,
+			Transport: transport,
 		},
+		backoff: time.Duration(cfg.RetryBackoffMs) * time.Millisecond,
+	}, nil
+}
+
+// get requests path and decodes the JSON response into out
+func (c *Client) get(path string, query url.Values, out interface{}) error {
+	body, err := c.do(path, query)
+	if err != nil {
+		return err
 	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to decode response from %s: %w", path, err)
+	}
+	return nil
 }
 
-// FrigateAPIEvent reflects the raw API response which is slightly different from the MQTT "after" payload
-// e.g. "top_score" vs "score", but for our purposes (id, camera, label, start/end) matches enough.
-type FrigateAPIEvent struct {
-	ID        string   `json:"id"`
-	Camera    string   `json:"camera"`
-	Label     string   `json:"label"`
-	StartTime float64  `json:"start_time"`
-	Zones     []string `json:"zones"`
-	EndTime   *float64 `json:"end_time"` // Pointer to handle null
+// do performs a GET request, retrying connection errors, 429 and 5xx
+// responses with exponential backoff. An expired login is renewed once.
+func (c *Client) do(path string, query url.Values) ([]byte, error) {
+	backoff := c.backoff
+	attempts := 0
+	renewed := false
+
+	for {
+		attempts++
+		body, status, err := c.attempt(path, query)
+		if err == nil {
+			switch {
+			case status == http.StatusOK:
+				return body, nil
+			case status == http.StatusNotFound:
+				return nil, fmt.Errorf("%s: %w", path, ErrNotFound)
+			case status == http.StatusUnauthorized && c.config.Login && !renewed:
+				renewed = true
+				attempts--
+				c.setToken("")
+				continue
+			case status == http.StatusTooManyRequests || status >= 500:
+				err = fmt.Errorf("%s returned status: %d", path, status)
+			default:
+				return nil, fmt.Errorf("%s returned status: %d", path, status)
+			}
+		}
+
+		if attempts > c.config.MaxRetries {
+			if attempts > 1 {
+				return nil, fmt.Errorf("giving up after %d attempts: %w", attempts, err)
+			}
+			return nil, err
+		}
+
+		logger.Debugf("Frigate API attempt %d failed: %v. Retrying in %v", attempts, err, backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
 }
 
-func (c *Client) GetActiveEvents() ([]models.FrigateEvent, error) {
-	url := fmt.Sprintf("%s/api/events?in_progress=1", c.config.URL)
+func (c *Client) attempt(path string, query url.Values) ([]byte, int, error) {
+	target := c.config.URL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to build request: %w", err)
+	}
+	if err := c.authorize(req); err != nil {
+		return nil, 0, err
+	}
 
-	resp, err := c.client.Get(url)
+	resp, err := c.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query frigate API: %w", err)
+		return nil, 0, fmt.Errorf("failed to query frigate API: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("api active check returned status: %d", resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read response: %w", err)
 	}
+	return body, resp.StatusCode, nil
+}
 
-	var apiEvents []FrigateAPIEvent
-	if err := json.NewDecoder(resp.Body).Decode(&apiEvents); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+// authorize adds credentials: a static token, a JWT from /api/login, or
+// basic auth, in that order of preference
+func (c *Client) authorize(req *http.Request) error {
+	switch {
+	case c.config.Token != "":
+		req.Header.Set("Authorization", "Bearer "+c.config.Token)
+	case c.config.Login:
+		token, err := c.loginToken()
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	case c.config.User != "":
+		req.SetBasicAuth(c.config.User, c.config.Password)
 	}
+	return nil
+}
 
-	var events []models.FrigateEvent
-	for _, ae := range apiEvents {
-		// Convert API struct to our internal Model
-		// The API represents the "current state", so we map it to "After"
+func (c *Client) loginToken() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-		endTime := 0.0
-		if ae.EndTime != nil {
-			endTime = *ae.EndTime
-		}
+	if c.token != "" {
+		return c.token, nil
+	}
+
+	body, err := json.Marshal(map[string]string{"user": c.config.User, "password": c.config.Password})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal login: %w", err)
+	}
+	resp, err := c.client.Post(c.config.URL+"/api/login", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to log in to frigate: %w", err)
+	}
+	defer resp.Body.Close()
 
-		evt := models.FrigateEvent{
-			Type: "update", // Assume update for existing ongoing events
-			After: models.FrigateEventState{
-				ID:           ae.ID,
-				Camera:       ae.Camera,
-				Label:        ae.Label,
-				StartTime:    ae.StartTime,
-				EnteredZones: ae.Zones,
-				EndTime:      endTime,
-			},
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("frigate login returned status: %d", resp.StatusCode)
+	}
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == loginCookie {
+			c.token = cookie.Value
+			return c.token, nil
 		}
-		events = append(events, evt)
 	}
+	return "", fmt.Errorf("frigate login response has no %s cookie", loginCookie)
+}
 
-	return events, nil
+func (c *Client) setToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
 }
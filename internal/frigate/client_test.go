@@ -0,0 +1,317 @@
+package frigate
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"frigate-custom-reviews/internal/models"
+)
+
+func newTestClient(t *testing.T, cfg models.FrigateConfig) *Client {
+	t.Helper()
+	if cfg.RetryBackoffMs == 0 {
+		cfg.RetryBackoffMs = 1
+	}
+	c, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	return c
+}
+
+func TestAuth(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  models.FrigateConfig
+		want string
+	}{
+		{name: "Bearer", cfg: models.FrigateConfig{Token: "abc"}, want: "Bearer abc"},
+		{name: "Basic", cfg: models.FrigateConfig{User: "admin", Password: "secret"}, want: "Basic YWRtaW46c2VjcmV0"},
+		{name: "None", cfg: models.FrigateConfig{}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Get("Authorization")
+				w.Write([]byte(`{}`))
+			}))
+			defer srv.Close()
+
+			tt.cfg.URL = srv.URL
+			if _, err := newTestClient(t, tt.cfg).GetConfig(); err != nil {
+				t.Fatalf("GetConfig: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Authorization = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuth_LoginRenewsExpiredToken(t *testing.T) {
+	var logins atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/login" {
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			if body["user"] != "admin" || body["password"] != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			n := logins.Add(1)
+			http.SetCookie(w, &http.Cookie{Name: loginCookie, Value: "jwt" + strconv.Itoa(int(n))})
+			return
+		}
+
+		// The first token expires after one request
+		if r.Header.Get("Authorization") != "Bearer jwt2" && !(r.Header.Get("Authorization") == "Bearer jwt1" && r.URL.Query().Get("first") == "1") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	c := newTestClient(t, models.FrigateConfig{URL: srv.URL, User: "admin", Password: "secret", Login: true})
+	var out map[string]interface{}
+	if err := c.get("/api/config", map[string][]string{"first": {"1"}}, &out); err != nil {
+		t.Fatalf("First request: %v", err)
+	}
+	if _, err := c.GetConfig(); err != nil {
+		t.Fatalf("Request after expiry: %v", err)
+	}
+	if logins.Load() != 2 {
+		t.Errorf("Expected 2 logins, got %d", logins.Load())
+	}
+
+	bad := newTestClient(t, models.FrigateConfig{URL: srv.URL, User: "admin", Password: "wrong", Login: true})
+	if _, err := bad.GetConfig(); err == nil {
+		t.Error("Expected an error with wrong credentials")
+	}
+}
+
+func TestRetries(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/config":
+			if calls.Add(1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte(`{}`))
+		default:
+			calls.Add(1)
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	c := newTestClient(t, models.FrigateConfig{URL: srv.URL, MaxRetries: 2})
+	if _, err := c.GetConfig(); err != nil {
+		t.Fatalf("Expected success on the third attempt: %v", err)
+	}
+
+	// Client errors are not retried
+	calls.Store(0)
+	if _, err := c.GetEvents(EventQuery{}); err == nil {
+		t.Fatal("Expected an error for 400")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("Expected 1 attempt for 400, got %d", calls.Load())
+	}
+
+	calls.Store(0)
+	c = newTestClient(t, models.FrigateConfig{URL: srv.URL, MaxRetries: 1})
+	if _, err := c.GetConfig(); err == nil {
+		t.Error("Expected to give up after 2 attempts")
+	}
+
+	// Retries are on by default, and can be turned off
+	calls.Store(1)
+	if _, err := newTestClient(t, models.FrigateConfig{URL: srv.URL}).GetConfig(); err != nil {
+		t.Errorf("Expected the default client to retry after a 5xx: %v", err)
+	}
+	calls.Store(1)
+	if _, err := newTestClient(t, models.FrigateConfig{URL: srv.URL, MaxRetries: -1}).GetConfig(); err == nil || calls.Load() != 2 {
+		t.Errorf("Expected a single attempt with retries disabled, got %d (%v)", calls.Load()-1, err)
+	}
+}
+
+// fakeEvents serves /api/events newest first, honouring the filters the
+// client sends, and records each request's query
+func fakeEvents(t *testing.T, events []FrigateAPIEvent, queries *[]map[string]string) *httptest.Server {
+	sort.Slice(events, func(i, j int) bool { return events[i].StartTime > events[j].StartTime })
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/events/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.URL.Path == "/api/events/b" {
+			json.NewEncoder(w).Encode(events[len(events)-2])
+			return
+		}
+
+		q := map[string]string{}
+		for k := range r.URL.Query() {
+			q[k] = r.URL.Query().Get(k)
+		}
+		*queries = append(*queries, q)
+
+		before, _ := strconv.ParseFloat(q["before"], 64)
+		limit, _ := strconv.Atoi(q["limit"])
+		page := []FrigateAPIEvent{}
+		for _, e := range events {
+			if before > 0 && e.StartTime >= before {
+				continue
+			}
+			if q["cameras"] != "" && q["cameras"] != e.Camera {
+				continue
+			}
+			if len(page) == limit {
+				break
+			}
+			page = append(page, e)
+		}
+		json.NewEncoder(w).Encode(page)
+	}))
+}
+
+func TestGetEvents_PaginatesAndFilters(t *testing.T) {
+	end := 1500.0
+	events := []FrigateAPIEvent{
+		{ID: "a", Camera: "cam1", StartTime: 1000, EndTime: &end},
+		{ID: "b", Camera: "cam1", StartTime: 1100, SubLabel: "alice"},
+		{ID: "c", Camera: "cam2", StartTime: 1200},
+		{ID: "d", Camera: "cam1", StartTime: 1300, Zones: []string{"porch"}},
+		{ID: "e", Camera: "cam1", StartTime: 1300}, // Same start as d, across a page boundary
+		{ID: "f", Camera: "cam1", StartTime: 1400},
+	}
+	var queries []map[string]string
+	srv := fakeEvents(t, events, &queries)
+	defer srv.Close()
+	c := newTestClient(t, models.FrigateConfig{URL: srv.URL})
+
+	got, err := c.GetEvents(EventQuery{Cameras: []string{"cam1"}, Labels: []string{"person", "car"}, After: 900, PageSize: 2})
+	if err != nil {
+		t.Fatalf("GetEvents: %v", err)
+	}
+	var ids []string
+	for _, e := range got {
+		ids = append(ids, e.ID)
+	}
+	sort.Strings(ids)
+	if strings.Join(ids, ",") != "a,b,d,e,f" {
+		t.Fatalf("Unexpected events %v", ids)
+	}
+	if got[len(got)-1].EndTime != 1500 {
+		t.Errorf("Expected end time from the API, got %v", got[len(got)-1].EndTime)
+	}
+	for _, e := range got {
+		if e.ID == "d" && (len(e.EnteredZones) != 1 || len(e.CurrentZones) != 1) {
+			t.Errorf("Expected an event in progress to be in its zones, got %+v", e)
+		}
+	}
+	if len(queries) < 3 {
+		t.Errorf("Expected several pages, got %d requests", len(queries))
+	}
+	q := queries[0]
+	if q["cameras"] != "cam1" || q["labels"] != "person,car" || q["after"] != "900" || q["limit"] != "2" || q["include_thumbnails"] != "0" {
+		t.Errorf("Unexpected query %v", q)
+	}
+
+	queries = nil
+	got, err = c.GetEvents(EventQuery{Limit: 3})
+	if err != nil || len(got) != 3 || got[0].ID != "f" {
+		t.Errorf("Expected the 3 newest events, got %v (%v)", got, err)
+	}
+	if len(queries) != 1 || queries[0]["limit"] != "3" {
+		t.Errorf("Expected one request limited to 3, got %v", queries)
+	}
+}
+
+func TestGetEvent(t *testing.T) {
+	events := []FrigateAPIEvent{{ID: "a", StartTime: 1}, {ID: "b", Camera: "cam1", Label: "person", SubLabel: "alice", StartTime: 2}, {ID: "c", StartTime: 3}}
+	var queries []map[string]string
+	srv := fakeEvents(t, events, &queries)
+	defer srv.Close()
+	c := newTestClient(t, models.FrigateConfig{URL: srv.URL + "/"})
+
+	got, err := c.GetEvent("b")
+	if err != nil {
+		t.Fatalf("GetEvent: %v", err)
+	}
+	if got.ID != "b" || got.SubLabel != "alice" || got.EndTime != 0 {
+		t.Errorf("Unexpected event %+v", got)
+	}
+
+	if _, err := c.GetEvent("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestGetConfig(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+			"objects": {"track": ["person"]},
+			"audio": {"enabled": false, "listen": ["bark"]},
+			"cameras": {
+				"doorbell": {
+					"enabled": true,
+					"objects": {"track": ["person", "package"]},
+					"audio": {"enabled": true, "listen": ["bark", "speech"]},
+					"zones": {"porch": {"coordinates": "0,0,1,1", "objects": ["person"]}}
+				}
+			}
+		}`))
+	}))
+	defer srv.Close()
+
+	cfg, err := newTestClient(t, models.FrigateConfig{URL: srv.URL}).GetConfig()
+	if err != nil {
+		t.Fatalf("GetConfig: %v", err)
+	}
+	cam, ok := cfg.Cameras["doorbell"]
+	if !ok || len(cam.Objects.Track) != 2 || !cam.Audio.Enabled || len(cam.Zones["porch"].Objects) != 1 {
+		t.Errorf("Unexpected camera config %+v", cam)
+	}
+	if len(cfg.Objects.Track) != 1 || cfg.Audio.Enabled {
+		t.Errorf("Unexpected global config %+v", cfg)
+	}
+}
+
+func TestTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	caPath := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caPath, caPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := newTestClient(t, models.FrigateConfig{URL: srv.URL}).GetConfig(); err == nil {
+		t.Error("Expected an untrusted certificate to fail")
+	}
+	if _, err := newTestClient(t, models.FrigateConfig{URL: srv.URL, TLS: models.TLSConfig{CAFile: caPath}}).GetConfig(); err != nil {
+		t.Errorf("Expected the configured CA to be trusted: %v", err)
+	}
+	if _, err := NewClient(models.FrigateConfig{URL: srv.URL, TLS: models.TLSConfig{CAFile: filepath.Join(t.TempDir(), "missing.pem")}}); err == nil {
+		t.Error("Expected a missing CA file to fail")
+	}
+}
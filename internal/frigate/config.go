@@ -0,0 +1,37 @@
+package frigate
+
+// Config is the part of Frigate's /api/config this service cares about
+type Config struct {
+	Cameras map[string]CameraConfig `json:"cameras"`
+	Objects ObjectsConfig           `json:"objects"` // Global defaults
+	Audio   AudioConfig             `json:"audio"`   // Global defaults
+}
+
+type CameraConfig struct {
+	Enabled *bool                 `json:"enabled"`
+	Zones   map[string]ZoneConfig `json:"zones"`
+	Objects ObjectsConfig         `json:"objects"`
+	Audio   AudioConfig           `json:"audio"`
+}
+
+type ZoneConfig struct {
+	Objects []string `json:"objects"` // Empty means every tracked object
+}
+
+type ObjectsConfig struct {
+	Track []string `json:"track"`
+}
+
+type AudioConfig struct {
+	Enabled bool     `json:"enabled"`
+	Listen  []string `json:"listen"`
+}
+
+// GetConfig fetches Frigate's running configuration
+func (c *Client) GetConfig() (*Config, error) {
+	var cfg Config
+	if err := c.get("/api/config", nil, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
@@ -0,0 +1,177 @@
+package frigate
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"frigate-custom-reviews/internal/models"
+)
+
+// defaultPageSize is the number of events requested per page
+const defaultPageSize = 100
+
+// FrigateAPIEvent reflects the raw API response which is slightly different from the MQTT "after" payload
+// e.g. "top_score" vs "score", but for our purposes (id, camera, label, start/end) matches enough.
+type FrigateAPIEvent struct {
+	ID            string            `json:"id"`
+	Camera        string            `json:"camera"`
+	Label         string            `json:"label"`
+	SubLabel      models.SubLabel   `json:"sub_label"`
+	FalsePositive bool              `json:"false_positive"`
+	StartTime     float64           `json:"start_time"`
+	Zones         []string          `json:"zones"`
+	EndTime       *float64          `json:"end_time"` // Pointer to handle null
+	Data          *models.EventData `json:"data"`
+}
+
+// EventQuery filters /api/events. Zero values are not sent.
+type EventQuery struct {
+	Cameras    []string
+	Labels     []string
+	Zones      []string
+	After      float64 // Events starting after this Unix time
+	Before     float64 // Events starting before this Unix time
+	InProgress bool    // Only events that have not ended
+	Limit      int     // Maximum number of events in total, 0 for all
+	PageSize   int     // Events per request, default 100
+}
+
+func (q EventQuery) values(before float64, limit int) url.Values {
+	v := url.Values{}
+	if len(q.Cameras) > 0 {
+		v.Set("cameras", strings.Join(q.Cameras, ","))
+	}
+	if len(q.Labels) > 0 {
+		v.Set("labels", strings.Join(q.Labels, ","))
+	}
+	if len(q.Zones) > 0 {
+		v.Set("zones", strings.Join(q.Zones, ","))
+	}
+	if q.After > 0 {
+		v.Set("after", formatTime(q.After))
+	}
+	if before > 0 {
+		v.Set("before", formatTime(before))
+	}
+	if q.InProgress {
+		v.Set("in_progress", "1")
+	}
+	v.Set("limit", strconv.Itoa(limit))
+	v.Set("include_thumbnails", "0")
+	return v
+}
+
+func formatTime(t float64) string {
+	return strconv.FormatFloat(t, 'f', -1, 64)
+}
+
+// GetEvents returns the events matching q, newest first. Frigate returns at
+// most one page per request, so pages are walked backwards by start time
+// until the results run out or q.Limit is reached.
+func (c *Client) GetEvents(q EventQuery) ([]models.FrigateEventState, error) {
+	pageSize := q.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+
+	var events []models.FrigateEventState
+	seen := make(map[string]bool)
+	before := q.Before
+
+	for {
+		limit := pageSize
+		if q.Limit > 0 {
+			limit = min(limit, q.Limit-len(events))
+		}
+
+		var page []FrigateAPIEvent
+		if err := c.get("/api/events", q.values(before, limit), &page); err != nil {
+			return nil, err
+		}
+
+		oldest := 0.0
+		for _, ae := range page {
+			if oldest == 0 || ae.StartTime < oldest {
+				oldest = ae.StartTime
+			}
+			if seen[ae.ID] {
+				continue
+			}
+			seen[ae.ID] = true
+			events = append(events, ae.toState())
+		}
+
+		if len(page) < limit || (q.Limit > 0 && len(events) >= q.Limit) {
+			return events, nil
+		}
+
+		// 'before' is exclusive, so the next page starts just after the
+		// oldest start time to catch events sharing it, and repeats are
+		// skipped above. If that makes no progress, step past the tie.
+		next := oldest + 0.001
+		if before != 0 && next >= before {
+			next = oldest
+		}
+		if next == before || next <= 0 {
+			return events, nil
+		}
+		before = next
+	}
+}
+
+// GetActiveEvents returns the events that are still in progress as updates,
+// ready to be fed to the engine
+func (c *Client) GetActiveEvents() ([]models.FrigateEvent, error) {
+	states, err := c.GetEvents(EventQuery{InProgress: true})
+	if err != nil {
+		return nil, err
+	}
+
+	var events []models.FrigateEvent
+	for _, state := range states {
+		// The API represents the "current state", so we map it to "After"
+		events = append(events, models.FrigateEvent{
+			Type:  "update", // Assume update for existing ongoing events
+			After: state,
+		})
+	}
+	return events, nil
+}
+
+// GetEvent returns the current state of a single event, including its end
+// time once it has ended. Unknown IDs return ErrNotFound.
+func (c *Client) GetEvent(id string) (models.FrigateEventState, error) {
+	var ae FrigateAPIEvent
+	if err := c.get("/api/events/"+url.PathEscape(id), nil, &ae); err != nil {
+		return models.FrigateEventState{}, err
+	}
+	return ae.toState(), nil
+}
+
+// toState converts an API event to our internal model. The API only lists
+// the zones an event entered; an event in progress is assumed to still be
+// in them, so zone_mode current and min_dwell can apply until an update
+// says otherwise.
+func (ae FrigateAPIEvent) toState() models.FrigateEventState {
+	endTime := 0.0
+	var currentZones []string
+	if ae.EndTime != nil {
+		endTime = *ae.EndTime
+	} else {
+		currentZones = ae.Zones
+	}
+
+	return models.FrigateEventState{
+		ID:            ae.ID,
+		Camera:        ae.Camera,
+		Label:         ae.Label,
+		SubLabel:      ae.SubLabel,
+		FalsePositive: ae.FalsePositive,
+		StartTime:     ae.StartTime,
+		EnteredZones:  ae.Zones,
+		CurrentZones:  currentZones,
+		EndTime:       endTime,
+		Data:          ae.Data,
+	}
+}
@@ -0,0 +1,158 @@
+package frigate
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"frigate-custom-reviews/internal/models"
+)
+
+// Problem is a profile setting that can never match anything Frigate reports
+type Problem struct {
+	Profile string
+	Field   string // "cameras", "required_zones" or "labels"
+	Value   string
+	Reason  string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("profile %q: %s %q %s", p.Profile, p.Field, p.Value, p.Reason)
+}
+
+// ValidateProfiles checks every camera, required zone and label of the
+// profiles against the Frigate config. Zones must belong to one of the
+// profile's cameras, labels must be tracked (or listened for, with audio
+// enabled) on one of them. Profiles without cameras are checked against all.
+func (c *Config) ValidateProfiles(profiles []models.Profile) []Problem {
+	var problems []Problem
+
+	for _, p := range profiles {
+		var cameras []string
+		for _, camera := range p.Cameras {
+			resolved := c.resolveCamera(camera)
+			if len(resolved) == 0 {
+				problems = append(problems, Problem{p.Name, "cameras", camera, "is not a Frigate camera"})
+			}
+			cameras = append(cameras, resolved...)
+		}
+		if len(p.Cameras) == 0 {
+			cameras = c.CameraNames()
+		}
+
+		for _, zone := range p.RequiredZones {
+			if !slices.ContainsFunc(cameras, func(camera string) bool {
+				_, ok := c.Cameras[camera].Zones[zone]
+				return ok
+			}) {
+				problems = append(problems, Problem{p.Name, "required_zones", zone, "is not a zone of " + describeCameras(p.Cameras)})
+			}
+		}
+
+		for _, label := range p.Labels {
+			if !slices.ContainsFunc(cameras, func(camera string) bool {
+				return slices.Contains(c.CameraLabels(camera), label)
+			}) {
+				problems = append(problems, Problem{p.Name, "labels", label, "is not tracked on " + describeCameras(p.Cameras)})
+			}
+		}
+	}
+
+	return problems
+}
+
+// resolveCamera returns the cameras a profile camera refers to: itself, or
+// for a bare name, that camera on every source
+func (c *Config) resolveCamera(name string) []string {
+	if _, ok := c.Cameras[name]; ok {
+		return []string{name}
+	}
+	var matches []string
+	for _, camera := range c.CameraNames() {
+		if _, bare, ok := strings.Cut(camera, "/"); ok && bare == name {
+			matches = append(matches, camera)
+		}
+	}
+	return matches
+}
+
+// Merge combines the configs of several Frigate sources, keyed by source
+// name, into one whose cameras are named like the engine qualifies them:
+// "<source>/<camera>", or bare for the unnamed source. Global object and
+// audio defaults are applied to each camera.
+func Merge(configs map[string]*Config) *Config {
+	merged := &Config{Cameras: make(map[string]CameraConfig)}
+	for source, cfg := range configs {
+		for name, cam := range cfg.Cameras {
+			if len(cam.Objects.Track) == 0 {
+				cam.Objects.Track = cfg.Objects.Track
+			}
+			if len(cam.Audio.Listen) == 0 {
+				cam.Audio.Listen = cfg.Audio.Listen
+			}
+			merged.Cameras[models.FrigateEventState{Source: source, Camera: name}.QualifiedCamera()] = cam
+		}
+	}
+	return merged
+}
+
+func describeCameras(cameras []string) string {
+	if len(cameras) == 0 {
+		return "any camera"
+	}
+	return strings.Join(cameras, ", ")
+}
+
+// CameraNames returns the configured cameras, sorted
+func (c *Config) CameraNames() []string {
+	names := make([]string, 0, len(c.Cameras))
+	for name := range c.Cameras {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CameraLabels returns the object labels tracked on camera, plus the audio
+// labels it listens for when audio detection is enabled, sorted
+func (c *Config) CameraLabels(camera string) []string {
+	cam, ok := c.Cameras[camera]
+	if !ok {
+		return nil
+	}
+
+	track := cam.Objects.Track
+	if len(track) == 0 {
+		track = c.Objects.Track
+	}
+	labels := slices.Clone(track)
+
+	if cam.Audio.Enabled {
+		listen := cam.Audio.Listen
+		if len(listen) == 0 {
+			listen = c.Audio.Listen
+		}
+		labels = append(labels, listen...)
+	}
+
+	sort.Strings(labels)
+	return slices.Compact(labels)
+}
+
+// Describe lists each camera with its zones and labels, one per line, to
+// help fix profiles flagged by ValidateProfiles
+func (c *Config) Describe() []string {
+	var lines []string
+	for _, camera := range c.CameraNames() {
+		zones := make([]string, 0, len(c.Cameras[camera].Zones))
+		for zone := range c.Cameras[camera].Zones {
+			zones = append(zones, zone)
+		}
+		sort.Strings(zones)
+
+		lines = append(lines, fmt.Sprintf("%s: zones [%s], labels [%s]",
+			camera, strings.Join(zones, ", "), strings.Join(c.CameraLabels(camera), ", ")))
+	}
+	return lines
+}
@@ -0,0 +1,107 @@
+package frigate
+
+import (
+	"slices"
+	"testing"
+
+	"frigate-custom-reviews/internal/models"
+)
+
+func testConfig() *Config {
+	return &Config{
+		Objects: ObjectsConfig{Track: []string{"person"}},
+		Audio:   AudioConfig{Listen: []string{"bark", "speech"}},
+		Cameras: map[string]CameraConfig{
+			"doorbell": {
+				Objects: ObjectsConfig{Track: []string{"person", "package"}},
+				Audio:   AudioConfig{Enabled: true},
+				Zones:   map[string]ZoneConfig{"porch": {}},
+			},
+			"driveway": {
+				Zones: map[string]ZoneConfig{"street": {}},
+			},
+		},
+	}
+}
+
+func TestValidateProfiles(t *testing.T) {
+	cfg := testConfig()
+
+	tests := []struct {
+		name    string
+		profile models.Profile
+		want    []string // Field:Value of each problem
+	}{
+		{
+			name:    "Valid",
+			profile: models.Profile{Cameras: []string{"doorbell"}, Labels: []string{"package", "bark"}, RequiredZones: []string{"porch"}},
+		},
+		{
+			name:    "Unknown Camera",
+			profile: models.Profile{Cameras: []string{"doorbel"}},
+			want:    []string{"cameras:doorbel"},
+		},
+		{
+			name:    "Zone Of Another Camera",
+			profile: models.Profile{Cameras: []string{"doorbell"}, RequiredZones: []string{"street"}},
+			want:    []string{"required_zones:street"},
+		},
+		{
+			name:    "Zone Without Cameras",
+			profile: models.Profile{RequiredZones: []string{"street", "garden"}},
+			want:    []string{"required_zones:garden"},
+		},
+		{
+			name:    "Label Not Tracked",
+			profile: models.Profile{Cameras: []string{"driveway"}, Labels: []string{"person", "package", "bark"}},
+			want:    []string{"labels:package", "labels:bark"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, p := range cfg.ValidateProfiles([]models.Profile{tt.profile}) {
+				got = append(got, p.Field+":"+p.Value)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ValidateProfiles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	want := []string{
+		"doorbell: zones [porch], labels [bark, package, person, speech]",
+		"driveway: zones [street], labels [person]",
+	}
+	if got := testConfig().Describe(); !slices.Equal(got, want) {
+		t.Errorf("Describe() = %q, want %q", got, want)
+	}
+}
+
+func TestValidateProfiles_MergedSources(t *testing.T) {
+	garage := &Config{
+		Objects: ObjectsConfig{Track: []string{"car"}},
+		Cameras: map[string]CameraConfig{"driveway": {Zones: map[string]ZoneConfig{"apron": {}}}},
+	}
+	cfg := Merge(map[string]*Config{"house": testConfig(), "garage": garage})
+
+	if got := cfg.CameraNames(); !slices.Equal(got, []string{"garage/driveway", "house/doorbell", "house/driveway"}) {
+		t.Fatalf("Unexpected merged cameras %v", got)
+	}
+
+	profiles := []models.Profile{
+		{Name: "any-driveway", Cameras: []string{"driveway"}, Labels: []string{"car"}, RequiredZones: []string{"apron", "street"}},
+		{Name: "garage-only", Cameras: []string{"garage/driveway"}, RequiredZones: []string{"street"}},
+		{Name: "typo", Cameras: []string{"garage/doorbell"}},
+	}
+	var got []string
+	for _, p := range cfg.ValidateProfiles(profiles) {
+		got = append(got, p.Profile+":"+p.Value)
+	}
+	if want := []string{"garage-only:street", "typo:garage/doorbell"}; !slices.Equal(got, want) {
+		t.Errorf("ValidateProfiles() = %v, want %v", got, want)
+	}
+}
@@ -1,49 +1,182 @@
 package models
 
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
 // Config defines the user settings
 type Config struct {
-	MQTT           MQTTConfig    `yaml:"mqtt"`
-	Frigate        FrigateConfig `yaml:"frigate"`
-	Logging        LoggingConfig `yaml:"logging"`
-	Profiles       []Profile     `yaml:"profiles"`
-	PublishUpdates bool          `yaml:"publish_updates"`
-	GhostTimeout   int           `yaml:"event_timeout"`
+	MQTT           MQTTConfig     `yaml:"mqtt"`
+	Frigate        FrigateConfig  `yaml:"frigate"`
+	Sources        []SourceConfig `yaml:"sources"` // Empty means one unnamed source from frigate and mqtt.frigate_events_topic
+	Logging        LoggingConfig  `yaml:"logging"`
+	Profiles       []Profile      `yaml:"profiles"`
+	Outputs        OutputsConfig  `yaml:"outputs"`
+	Audit          AuditConfig    `yaml:"audit"`
+	PublishUpdates bool           `yaml:"publish_updates"`
+	GhostTimeout   int            `yaml:"event_timeout"`
+
+	ReconcileInterval int `yaml:"reconcile_interval"` // Seconds between API checks of tracked events, negative disables
 }
 
 type LoggingConfig struct {
 	Level string `yaml:"level"`
 }
 
+// AuditConfig enables the JSONL audit log of ingested events and decisions
+type AuditConfig struct {
+	Path       string `yaml:"path"`        // Empty disables the audit log
+	MaxSizeMB  int    `yaml:"max_size_mb"` // Rotate once the file reaches this size
+	MaxBackups int    `yaml:"max_backups"` // Rotated files to keep
+}
+
 type MQTTConfig struct {
-	Broker              string `yaml:"broker"`
-	ClientID            string `yaml:"client_id"`
-	User                string `yaml:"user"`
-	Password            string `yaml:"password"`
-	FrigateEventsTopic  string `yaml:"frigate_events_topic"`
-	ReviewsPublishTopic string `yaml:"reviews_publish_topic"`
+	Broker              string        `yaml:"broker"`
+	ClientID            string        `yaml:"client_id"`
+	User                string        `yaml:"user"`
+	Password            string        `yaml:"password"`
+	FrigateEventsTopic  string        `yaml:"frigate_events_topic"`
+	FrigateReviewsTopic string        `yaml:"frigate_reviews_topic"` // Frigate's own review segments, empty disables
+	ReviewsPublishTopic string        `yaml:"reviews_publish_topic"` // Supports {profile} and {review_id}
+	StateTopic          string        `yaml:"state_topic"`           // Retained ReviewState, supports {profile}
+	AvailabilityTopic   string        `yaml:"availability_topic"`    // "online" / "offline" (LWT)
+	QoS                 MQTTQoSConfig `yaml:"qos"`
+	IngestQueueSize     int           `yaml:"ingest_queue_size"` // Buffered Frigate events before overflow
+	OverflowPolicy      string        `yaml:"overflow_policy"`   // drop_oldest, drop_newest or coalesce
+	TLS                 TLSConfig     `yaml:"tls"`
+	PersistentSession   bool          `yaml:"persistent_session"` // Keep subscriptions and queued QoS 1+ messages across reconnects
+}
+
+// TLSConfig secures a client connection. For MQTT it applies to ssl://,
+// tls://, mqtts:// and wss:// brokers, and to any broker once a field is set.
+type TLSConfig struct {
+	CAFile             string `yaml:"ca_file"`              // PEM bundle to verify the server, default is the system pool
+	CertFile           string `yaml:"cert_file"`            // PEM client certificate
+	KeyFile            string `yaml:"key_file"`             // PEM client key
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"` // Lab use only
+}
+
+// MQTTQoSConfig sets the QoS level used for each kind of MQTT message
+type MQTTQoSConfig struct {
+	Events       byte `yaml:"events"`       // Subscription to frigate/events
+	Reviews      byte `yaml:"reviews"`      // Review lifecycle messages
+	State        byte `yaml:"state"`        // Retained review state
+	Availability byte `yaml:"availability"` // Online / offline status
 }
 
 type FrigateConfig struct {
-	URL string `yaml:"url"`
+	URL            string    `yaml:"url"`
+	Token          string    `yaml:"token"`            // Bearer token, e.g. a Frigate JWT
+	User           string    `yaml:"user"`             // Basic auth, or the /api/login user with login: true
+	Password       string    `yaml:"password"`         // Basic auth or login password
+	Login          bool      `yaml:"login"`            // Exchange user/password for a JWT (Frigate 0.14+ auth)
+	TLS            TLSConfig `yaml:"tls"`              // For https:// URLs
+	Timeout        int       `yaml:"timeout"`          // Seconds per request
+	MaxRetries     int       `yaml:"max_retries"`      // Attempts after a failed request, default 2, negative disables retries
+	RetryBackoffMs int       `yaml:"retry_backoff_ms"` // Initial backoff, doubled per retry
 }
 
- type TimeRange struct {
- 	Start string `yaml:"start"` // "05:00"
- 	End   string `yaml:"end"`   // "21:00"
- }
+// SourceConfig is one Frigate instance. Its cameras are known to profiles
+// and reviews as "<name>/<camera>".
+type SourceConfig struct {
+	Name          string `yaml:"name"`
+	EventsTopic   string `yaml:"events_topic"`  // e.g. "garage/events" for a topic_prefix of "garage"
+	ReviewsTopic  string `yaml:"reviews_topic"` // e.g. "garage/reviews", empty disables
+	FrigateConfig `yaml:",inline"`
+}
+
+// OutputsConfig enables the sinks that receive review lifecycle messages
+type OutputsConfig struct {
+	MQTT     MQTTOutputConfig   `yaml:"mqtt"`
+	Webhooks []WebhookConfig    `yaml:"webhooks"`
+	Files    []FileOutputConfig `yaml:"files"`
+}
+
+// OutputOptions are shared by every output sink
+type OutputOptions struct {
+	Profiles   []string `yaml:"profiles"`    // Only these profiles, empty means all
+	Types      []string `yaml:"types"`       // Only these message types, empty means all
+	BufferSize int      `yaml:"buffer_size"` // Pending messages before dropping
+}
+
+// MQTTOutputConfig controls the MQTT sink. Topics are taken from MQTTConfig.
+type MQTTOutputConfig struct {
+	OutputOptions `yaml:",inline"`
+	Disabled      bool `yaml:"disabled"`
+}
 
- type Profile struct {
- 	Name          string      `yaml:"name"`           // "front_yard"
- 	Cameras       []string    `yaml:"cameras"`        // ["doorbell", "driveway"]
- 	Labels        []string    `yaml:"labels"`         // ["person", "dog"]
- 	RequiredZones []string    `yaml:"required_zones"` // ["driveway", "road"]
- 	TimeRanges    []TimeRange `yaml:"time_ranges"`    // [{start: "05:00", end: "21:00"}]
- 	Gap           int         `yaml:"gap"`            // 30
- }
+// WebhookConfig defines an HTTP endpoint notified of review lifecycle messages
+type WebhookConfig struct {
+	OutputOptions  `yaml:",inline"`
+	Name           string            `yaml:"name"`
+	URL            string            `yaml:"url"`
+	Method         string            `yaml:"method"`           // Default POST
+	Headers        map[string]string `yaml:"headers"`          // Extra request headers
+	Template       string            `yaml:"template"`         // Go template for the body, default is the JSON payload
+	Secret         string            `yaml:"secret"`           // HMAC-SHA256 signing key
+	Timeout        int               `yaml:"timeout"`          // Seconds per attempt
+	MaxRetries     int               `yaml:"max_retries"`      // Attempts after the first failure
+	RetryBackoffMs int               `yaml:"retry_backoff_ms"` // Initial backoff, doubled per retry
+	DeadLetterFile string            `yaml:"dead_letter_file"` // JSONL log of undeliverable messages
+}
+
+// FileOutputConfig appends review messages to a newline-delimited JSON file
+type FileOutputConfig struct {
+	OutputOptions `yaml:",inline"`
+	Path          string `yaml:"path"`
+}
+
+type TimeRange struct {
+	Start string `yaml:"start"` // "05:00"
+	End   string `yaml:"end"`   // "21:00"
+}
+
+type Profile struct {
+	Name          string      `yaml:"name"`           // "front_yard"
+	Cameras       []string    `yaml:"cameras"`        // ["doorbell", "driveway"]
+	Labels        []string    `yaml:"labels"`         // ["person", "dog"]
+	CustomLabels  []string    `yaml:"custom_labels"`  // Labels of external events, matched like labels but unknown to Frigate's config
+	SubLabels     []string    `yaml:"sub_labels"`     // ["alice"], requires a matching sub label when set
+	RequiredZones []string    `yaml:"required_zones"` // ["driveway", "road"]
+	ZoneMode      string      `yaml:"zone_mode"`      // How required_zones apply, see ZoneMode* (default entered)
+	TimeRanges    []TimeRange `yaml:"time_ranges"`    // [{start: "05:00", end: "21:00"}]
+	Gap           int         `yaml:"gap"`            // 30
+	EscalateAfter int         `yaml:"escalate_after"` // Seconds active before an 'escalate' message, 0 disables
+	Input         string      `yaml:"input"`          // InputEvents (default) or InputReviews
+	Severities    []string    `yaml:"severities"`     // ["alert"], only Frigate review segments with these severities
+	PointDuration int         `yaml:"point_duration"` // Seconds after its start an audio or external event is treated as ended
+
+	MinDwell        int `yaml:"min_dwell"`        // Seconds an object must stay in a required zone (or any zone) to qualify
+	LoiterThreshold int `yaml:"loiter_threshold"` // Seconds in a zone after which the review is flagged loitering and escalated
+
+	UpdateInterval int `yaml:"update_interval_ms"` // Minimum time between 'update' messages, 0 sends each change
+}
+
+// Profile zone modes: which zones of an event required_zones are checked against
+const (
+	ZoneModeEntered     = "entered"      // Any zone the object entered
+	ZoneModeCurrent     = "current"      // Zones the object is in now
+	ZoneModeSinceReview = "since_review" // Zones entered after the profile's review opened
+)
+
+// Profile inputs: what a profile stitches into reviews
+const (
+	InputEvents  = "events"  // Tracked objects from frigate/events
+	InputReviews = "reviews" // Review segments from frigate/reviews
+)
+
+// Frigate review segment severities, from lowest to highest
+const (
+	SeverityDetection = "detection"
+	SeverityAlert     = "alert"
+)
 
 type LinkedEventSummary struct {
 	ID     string `json:"id"`
 	Camera string `json:"camera"`
+	Active bool   `json:"active"` // Whether the event has not ended yet
 }
 
 // ReviewState represents the "Data" block in the JSON payload
@@ -58,14 +191,60 @@ type ReviewState struct {
 	LinkedEvents []LinkedEventSummary `json:"linked_events"` // List of Frigate IDs with Camera
 	Objects      []string             `json:"objects"`
 	Cameras      []string             `json:"cameras"`
-	Zones        []string             `json:"zones"`
+	Zones        []string             `json:"zones"`              // Every zone visited by the linked events
+	CurrentZones []string             `json:"current_zones"`      // Zones occupied by active events now
+	Severity     string               `json:"severity,omitempty"` // Highest severity of the linked review segments
+	Loitering    bool                 `json:"loitering"`          // An object stayed past the profile's loiter_threshold
 }
 
+// Review lifecycle message types
+const (
+	MessageNew      = "new"
+	MessageUpdate   = "update"
+	MessageEnd      = "end"
+	MessageEscalate = "escalate"
+)
+
 // MessagePayload represents the actual MQTT message
 type MessagePayload struct {
-	Type   string       `json:"type"` // "new", "update", "end"
-	Before *ReviewState `json:"before"`
-	After  *ReviewState `json:"after"`
+	Type          string         `json:"type"` // "new", "update", "end", "escalate"
+	Before        *ReviewState   `json:"before"`
+	After         *ReviewState   `json:"after"`
+	RemovedEvents []RemovedEvent `json:"removed_events,omitempty"`
+	Changes       []string       `json:"changes,omitempty"` // What differs from Before, see Change* constants
+}
+
+// Change descriptors listed in MessagePayload.Changes
+const (
+	ChangeAddedEvent   = "added_event"
+	ChangeRemovedEvent = "removed_event"
+	ChangeEventEnded   = "event_ended"
+	ChangeNewCamera    = "new_camera"
+	ChangeNewZone      = "new_zone"
+	ChangeNewObject    = "new_object"
+	ChangeSeverity     = "severity_changed"
+	ChangeLoitering    = "loitering"
+	ChangeCurrentZones = "current_zones"
+)
+
+// RemovedEvent describes an event dropped from a review because a later
+// update no longer matched the profile
+type RemovedEvent struct {
+	ID     string `json:"id"`
+	Camera string `json:"camera"`
+	Reason string `json:"reason"` // The failed criterion, e.g. "label" or "false_positive"
+}
+
+// Frigate event types, reported in the event's data.type
+const (
+	EventTypeObject   = "object"
+	EventTypeAudio    = "audio"
+	EventTypeExternal = "api" // Created through Frigate's API, e.g. by an integration
+)
+
+// EventData is the part of Frigate's event data this service uses
+type EventData struct {
+	Type string `json:"type"`
 }
 
 // FrigateEvent matches the Frigate JSON payload
@@ -76,11 +255,143 @@ type FrigateEvent struct {
 }
 
 type FrigateEventState struct {
-	ID           string   `json:"id"`
-	Camera       string   `json:"camera"`
-	Label        string   `json:"label"`
-	StartTime    float64  `json:"start_time"`
-	EndTime      float64  `json:"end_time,omitempty"` // 0 or null if active? usually 0 or missing in Frigate
-	CurrentZones []string `json:"current_zones"`
-	EnteredZones []string `json:"entered_zones"`
+	ID            string     `json:"id"`
+	Camera        string     `json:"camera"`
+	Label         string     `json:"label"`
+	SubLabel      SubLabel   `json:"sub_label,omitempty"`
+	FalsePositive bool       `json:"false_positive,omitempty"`
+	StartTime     float64    `json:"start_time"`
+	EndTime       float64    `json:"end_time,omitempty"`   // 0 or null if active? usually 0 or missing in Frigate
+	FrameTime     float64    `json:"frame_time,omitempty"` // Time of the frame this state was computed from
+	CurrentZones  []string   `json:"current_zones"`
+	EnteredZones  []string   `json:"entered_zones"`
+	Data          *EventData `json:"data,omitempty"` // Tracked objects may omit it
+
+	// Source is the name of the Frigate instance that reported the event. It
+	// is set on ingest, not sent by Frigate.
+	Source string `json:"source,omitempty"`
+
+	// Set for review segments from frigate/reviews, which summarize several
+	// objects. See FrigateReviewSegment.State.
+	Severity   string   `json:"severity,omitempty"`
+	Objects    []string `json:"objects,omitempty"`    // Object and audio labels
+	SubLabels  []string `json:"sub_labels,omitempty"` // Recognized sub labels
+	Detections []string `json:"detections,omitempty"` // IDs of the events in the segment
+}
+
+// QualifiedCamera returns "<source>/<camera>", or the bare camera for the
+// unnamed source
+func (s FrigateEventState) QualifiedCamera() string {
+	if s.Source == "" {
+		return s.Camera
+	}
+	return s.Source + "/" + s.Camera
+}
+
+// IsPointInTime reports whether the event is an audio detection or an
+// external event. These have no zones, and Frigate's end time, if any, says
+// little about when the incident happened.
+func (s FrigateEventState) IsPointInTime() bool {
+	return s.Data != nil && (s.Data.Type == EventTypeAudio || s.Data.Type == EventTypeExternal)
+}
+
+// IsReviewSegment reports whether the state is a Frigate review segment
+// rather than a tracked object
+func (s FrigateEventState) IsReviewSegment() bool {
+	return s.Severity != ""
+}
+
+// Labels returns every label of a review segment, or the label of an event
+func (s FrigateEventState) Labels() []string {
+	if s.IsReviewSegment() {
+		return s.Objects
+	}
+	return []string{s.Label}
+}
+
+// SubLabelNames returns the sub labels of a review segment, or the sub
+// label of an event if it has one
+func (s FrigateEventState) SubLabelNames() []string {
+	if s.IsReviewSegment() {
+		return s.SubLabels
+	}
+	if s.SubLabel == "" {
+		return nil
+	}
+	return []string{string(s.SubLabel)}
+}
+
+// FrigateReview matches the payload of Frigate's frigate/reviews topic (0.14+)
+type FrigateReview struct {
+	Type   string               `json:"type"`
+	Before FrigateReviewSegment `json:"before"`
+	After  FrigateReviewSegment `json:"after"`
+}
+
+type FrigateReviewSegment struct {
+	ID        string            `json:"id"`
+	Camera    string            `json:"camera"`
+	StartTime float64           `json:"start_time"`
+	EndTime   float64           `json:"end_time,omitempty"` // null while the segment is active
+	Severity  string            `json:"severity"`           // SeverityAlert or SeverityDetection
+	Data      FrigateReviewData `json:"data"`
+}
+
+type FrigateReviewData struct {
+	Detections []string `json:"detections"`
+	Objects    []string `json:"objects"`
+	SubLabels  []string `json:"sub_labels"`
+	Zones      []string `json:"zones"`
+	Audio      []string `json:"audio"`
+}
+
+// Event converts a review message so the engine can ingest it like an event
+func (r FrigateReview) Event() FrigateEvent {
+	return FrigateEvent{Type: r.Type, Before: r.Before.State(), After: r.After.State()}
+}
+
+// State describes the segment as an event state. Label is the first object
+// (or audio) label, for logs; Labels returns all of them.
+func (s FrigateReviewSegment) State() FrigateEventState {
+	objects := append(slices.Clone(s.Data.Objects), s.Data.Audio...)
+	state := FrigateEventState{
+		ID:           s.ID,
+		Camera:       s.Camera,
+		StartTime:    s.StartTime,
+		EndTime:      s.EndTime,
+		CurrentZones: s.Data.Zones,
+		EnteredZones: s.Data.Zones,
+		Severity:     s.Severity,
+		Objects:      objects,
+		SubLabels:    s.Data.SubLabels,
+		Detections:   s.Data.Detections,
+	}
+	if len(objects) > 0 {
+		state.Label = objects[0]
+	}
+	return state
+}
+
+// SubLabel accepts Frigate's sub_label as either a plain string or a
+// [name, score] pair (Frigate 0.13+), keeping only the name.
+type SubLabel string
+
+func (s *SubLabel) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*s = SubLabel(name)
+		return nil
+	}
+
+	var pair []interface{}
+	if err := json.Unmarshal(data, &pair); err != nil {
+		return fmt.Errorf("invalid sub_label: %s", data)
+	}
+	*s = ""
+	if len(pair) > 0 {
+		if name, ok := pair[0].(string); ok {
+			*s = SubLabel(name)
+		}
+	}
+	return nil
 }
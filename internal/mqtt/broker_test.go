@@ -0,0 +1,161 @@
+package mqtt
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"frigate-custom-reviews/internal/models"
+)
+
+// MQTT 3.1.1 control packet types used by the stub broker
+const (
+	packetConnect    = 1
+	packetSubscribe  = 8
+	packetPingReq    = 12
+	packetDisconnect = 14
+)
+
+// stubBroker speaks just enough MQTT for the client to connect, subscribe
+// and keep alive. Subscribed topics are reported on subscribed.
+type stubBroker struct {
+	addr       string
+	subscribed chan string
+
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+func startStubBroker(t *testing.T, ln net.Listener) *stubBroker {
+	t.Helper()
+	b := &stubBroker{addr: ln.Addr().String(), subscribed: make(chan string, 10)}
+	t.Cleanup(func() {
+		ln.Close()
+		b.drop()
+	})
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			b.mu.Lock()
+			b.conns = append(b.conns, conn)
+			b.mu.Unlock()
+			go b.serve(conn)
+		}
+	}()
+
+	return b
+}
+
+func (b *stubBroker) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		header, body, err := readPacket(conn)
+		if err != nil {
+			return
+		}
+
+		switch header >> 4 {
+		case packetConnect:
+			conn.Write([]byte{0x20, 0x02, 0x00, 0x00})
+		case packetSubscribe:
+			// Packet ID, then the first topic filter as a length-prefixed string
+			topicLen := binary.BigEndian.Uint16(body[2:4])
+			conn.Write([]byte{0x90, 0x03, body[0], body[1], 0x00})
+			b.subscribed <- string(body[4 : 4+topicLen])
+		case packetPingReq:
+			conn.Write([]byte{0xd0, 0x00})
+		case packetDisconnect:
+			return
+		}
+	}
+}
+
+// drop closes every open connection, as a broker restart would
+func (b *stubBroker) drop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, conn := range b.conns {
+		conn.Close()
+	}
+	b.conns = nil
+}
+
+// readPacket reads one MQTT packet: fixed header, varint length, body
+func readPacket(r io.Reader) (byte, []byte, error) {
+	buf := make([]byte, 1)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return 0, nil, err
+	}
+	header := buf[0]
+
+	length, multiplier := 0, 1
+	for {
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return 0, nil, err
+		}
+		length += int(buf[0]&0x7f) * multiplier
+		if buf[0]&0x80 == 0 {
+			break
+		}
+		multiplier *= 128
+	}
+
+	body := make([]byte, length)
+	_, err := io.ReadFull(r, body)
+	return header, body, err
+}
+
+func TestClient_RestoresSubscriptionsOnReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	broker := startStubBroker(t, ln)
+
+	c, err := NewClient(models.MQTTConfig{Broker: "tcp://" + broker.addr, ClientID: "reconnect-test"})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	reconnected := make(chan struct{}, 1)
+	c.OnReconnect(func() { reconnected <- struct{}{} })
+
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer c.client.Disconnect(0)
+
+	if err := c.SubscribeRaw("frigate/events", func(string, []byte) {}); err != nil {
+		t.Fatalf("SubscribeRaw: %v", err)
+	}
+	if topic := <-broker.subscribed; topic != "frigate/events" {
+		t.Fatalf("Unexpected subscription %q", topic)
+	}
+	select {
+	case <-reconnected:
+		t.Fatal("OnReconnect must not run for the first connection")
+	default:
+	}
+
+	broker.drop()
+
+	select {
+	case topic := <-broker.subscribed:
+		if topic != "frigate/events" {
+			t.Errorf("Unexpected subscription after reconnect %q", topic)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Subscription was not restored after reconnect")
+	}
+	select {
+	case <-reconnected:
+	case <-time.After(time.Second):
+		t.Fatal("OnReconnect was not called")
+	}
+}
@@ -3,19 +3,38 @@ package mqtt
 import (
 	"encoding/json"
 	"fmt"
+	"slices"
+	"sync"
 
 	"frigate-custom-reviews/internal/logger"
 	"frigate-custom-reviews/internal/models"
+	"frigate-custom-reviews/internal/tlsconfig"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 )
 
+const (
+	availabilityOnline  = "online"
+	availabilityOffline = "offline"
+)
+
 type Client struct {
 	client mqtt.Client
 	config models.MQTTConfig
+	queue  *IngestQueue
+
+	mu            sync.Mutex
+	subscriptions map[string]mqtt.MessageHandler // Restored on reconnect
+	onReconnect   []func()
+	connected     bool // Whether the first connection was made
 }
 
-func NewClient(cfg models.MQTTConfig) *Client {
+// NewClient configures a client for cfg.Broker, which may be a tcp://,
+// ssl:// (tls://, mqtts://), ws:// or wss:// URL. It fails only if the TLS
+// settings cannot be loaded.
+func NewClient(cfg models.MQTTConfig) (*Client, error) {
+	c := &Client{config: cfg, subscriptions: make(map[string]mqtt.MessageHandler)}
+
 	opts := mqtt.NewClientOptions()
 	opts.AddBroker(cfg.Broker)
 	opts.SetClientID(cfg.ClientID)
@@ -25,19 +44,37 @@ func NewClient(cfg models.MQTTConfig) *Client {
 		opts.SetPassword(cfg.Password)
 	}
 
+	tlsConfig, err := tlsconfig.Build(cfg.TLS)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		opts.SetTLSConfig(tlsConfig)
+	}
+
+	// A persistent session needs a stable client ID, the broker keeps our
+	// subscriptions and queues QoS 1+ messages while we are away
+	opts.SetCleanSession(!cfg.PersistentSession)
+	opts.SetResumeSubs(cfg.PersistentSession)
+
+	// The broker publishes "offline" on our behalf if the connection drops
+	if cfg.AvailabilityTopic != "" {
+		opts.SetWill(cfg.AvailabilityTopic, availabilityOffline, cfg.QoS.Availability, true)
+	}
+
 	opts.SetAutoReconnect(true)
-	opts.SetOnConnectHandler(func(c mqtt.Client) {
+	opts.SetOnConnectHandler(func(mc mqtt.Client) {
 		logger.Infof("Connected to MQTT broker at %s", cfg.Broker)
+		// Runs on every (re)connect, overwriting the LWT left by a dropped connection
+		c.publishAvailability(availabilityOnline)
+		c.handleConnect()
 	})
-	opts.SetConnectionLostHandler(func(c mqtt.Client, err error) {
+	opts.SetConnectionLostHandler(func(mc mqtt.Client, err error) {
 		logger.Warnf("Lost connection to MQTT broker: %v", err)
 	})
 
-	client := mqtt.NewClient(opts)
-	return &Client{
-		client: client,
-		config: cfg,
-	}
+	c.client = mqtt.NewClient(opts)
+	return c, nil
 }
 
 func (c *Client) Connect() error {
@@ -48,37 +85,163 @@ func (c *Client) Connect() error {
 	return nil
 }
 
-func (c *Client) Subscribe(ingestChan chan<- models.FrigateEvent) error {
-	token := c.client.Subscribe(c.config.FrigateEventsTopic, 0, func(client mqtt.Client, msg mqtt.Message) {
-		var event models.FrigateEvent
-		if err := json.Unmarshal(msg.Payload(), &event); err != nil {
-			logger.Errorf("Failed to unmarshal Frigate event: %v", err)
-			return
+// Subscribe decodes the events of every Frigate source into a bounded
+// ingest queue that feeds ingestChan, so a slow consumer never blocks the
+// MQTT connection. Events are tagged with their source's name.
+func (c *Client) Subscribe(ingestChan chan<- models.FrigateEvent, sources []models.SourceConfig) error {
+	queue, err := NewIngestQueue(c.config.IngestQueueSize, c.config.OverflowPolicy)
+	if err != nil {
+		return err
+	}
+	c.queue = queue
+	go queue.Forward(ingestChan)
+
+	for _, src := range sources {
+		name := src.Name
+		err := c.SubscribeRaw(src.EventsTopic, func(topic string, payload []byte) {
+			var event models.FrigateEvent
+			if err := json.Unmarshal(payload, &event); err != nil {
+				logger.Errorf("Failed to unmarshal Frigate event: %v", err)
+				return
+			}
+			event.Before.Source = name
+			event.After.Source = name
+			queue.Push(event)
+		})
+		if err != nil {
+			return err
 		}
-		ingestChan <- event
-	})
 
+		if src.ReviewsTopic == "" {
+			continue
+		}
+		err = c.SubscribeRaw(src.ReviewsTopic, func(topic string, payload []byte) {
+			var review models.FrigateReview
+			if err := json.Unmarshal(payload, &review); err != nil {
+				logger.Errorf("Failed to unmarshal Frigate review: %v", err)
+				return
+			}
+			event := review.Event()
+			event.Before.Source = name
+			event.After.Source = name
+			queue.Push(event)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IngestStats returns the ingest queue overflow counters
+func (c *Client) IngestStats() QueueStats {
+	if c.queue == nil {
+		return QueueStats{}
+	}
+	return c.queue.Stats()
+}
+
+// SubscribeRaw subscribes to topic (wildcards allowed) and hands each
+// message's topic and undecoded payload to handler. The subscription is
+// restored after a reconnect.
+func (c *Client) SubscribeRaw(topic string, handler func(topic string, payload []byte)) error {
+	callback := func(client mqtt.Client, msg mqtt.Message) {
+		handler(msg.Topic(), msg.Payload())
+	}
+
+	c.mu.Lock()
+	c.subscriptions[topic] = callback
+	c.mu.Unlock()
+
+	return c.subscribe(topic, callback)
+}
+
+func (c *Client) subscribe(topic string, callback mqtt.MessageHandler) error {
+	token := c.client.Subscribe(topic, c.config.QoS.Events, callback)
 	if token.Wait() && token.Error() != nil {
 		return token.Error()
 	}
 
-	logger.Infof("Subscribed to topic: %s", c.config.FrigateEventsTopic)
+	logger.Infof("Subscribed to topic: %s", topic)
 	return nil
 }
 
-func (c *Client) Publish(topic string, payload interface{}) error {
-	data, err := json.Marshal(payload)
-	if err != nil {
-		return fmt.Errorf("failed to marshal payload: %w", err)
+// OnReconnect registers fn to run after the connection was re-established
+// and subscriptions restored, e.g. to resync state missed while offline.
+// It is not called for the first connection.
+func (c *Client) OnReconnect(fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onReconnect = append(c.onReconnect, fn)
+}
+
+// handleConnect restores subscriptions and runs the reconnect hooks. Paho
+// calls it in its own goroutine, so it may block.
+func (c *Client) handleConnect() {
+	c.mu.Lock()
+	reconnect := c.connected
+	c.connected = true
+	subscriptions := make(map[string]mqtt.MessageHandler, len(c.subscriptions))
+	for topic, callback := range c.subscriptions {
+		subscriptions[topic] = callback
+	}
+	hooks := slices.Clone(c.onReconnect)
+	c.mu.Unlock()
+
+	if !reconnect {
+		return
+	}
+
+	// A clean session loses subscriptions, and a persistent one may have
+	// expired on the broker, so always subscribe again
+	for topic, callback := range subscriptions {
+		if err := c.subscribe(topic, callback); err != nil {
+			logger.Errorf("Failed to restore subscription to %s: %v", topic, err)
+		}
+	}
+
+	for _, fn := range hooks {
+		fn()
+	}
+}
+
+// Publish sends payload to topic. Strings and byte slices are sent as-is,
+// anything else is encoded as JSON.
+func (c *Client) Publish(topic string, qos byte, retained bool, payload interface{}) error {
+	var data []byte
+	switch p := payload.(type) {
+	case string:
+		data = []byte(p)
+	case []byte:
+		data = p
+	default:
+		var err error
+		data, err = json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal payload: %w", err)
+		}
 	}
 
-	token := c.client.Publish(topic, 0, false, data)
+	token := c.client.Publish(topic, qos, retained, data)
 	if token.Wait() && token.Error() != nil {
 		return token.Error()
 	}
 	return nil
 }
 
+func (c *Client) publishAvailability(status string) {
+	if c.config.AvailabilityTopic == "" {
+		return
+	}
+	if err := c.Publish(c.config.AvailabilityTopic, c.config.QoS.Availability, true, status); err != nil {
+		logger.Warnf("Failed to publish availability '%s': %v", status, err)
+	}
+}
+
 func (c *Client) Disconnect() {
+	// A clean disconnect does not trigger the LWT, so announce it ourselves
+	if c.client.IsConnected() {
+		c.publishAvailability(availabilityOffline)
+	}
 	c.client.Disconnect(250)
 }
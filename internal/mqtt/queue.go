@@ -0,0 +1,148 @@
+package mqtt
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"frigate-custom-reviews/internal/logger"
+	"frigate-custom-reviews/internal/models"
+)
+
+// Overflow policies for a full ingest queue
+const (
+	OverflowDropOldest = "drop_oldest" // Discard the oldest queued message
+	OverflowDropNewest = "drop_newest" // Discard the incoming message
+	OverflowCoalesce   = "coalesce"    // Replace a queued message for the same event, else drop oldest
+)
+
+// overflowWarnInterval limits how often overflow warnings are logged
+const overflowWarnInterval = 10 * time.Second
+
+// QueueStats counts ingest queue overflows
+type QueueStats struct {
+	Dropped   int64
+	Coalesced int64
+}
+
+// IngestQueue is a bounded buffer between the paho message callback and the
+// engine. Push never blocks, so a stalled engine can't stall paho's router
+// and time out the connection; instead the overflow policy decides what to lose.
+type IngestQueue struct {
+	size   int
+	policy string
+
+	mu       sync.Mutex
+	items    []models.FrigateEvent
+	lastWarn time.Time
+	ready    chan struct{} // Signalled (non-blocking) whenever items are added
+
+	dropped   atomic.Int64
+	coalesced atomic.Int64
+}
+
+func NewIngestQueue(size int, policy string) (*IngestQueue, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("ingest queue size must be positive, got %d", size)
+	}
+	switch policy {
+	case OverflowDropOldest, OverflowDropNewest, OverflowCoalesce:
+	default:
+		return nil, fmt.Errorf("unknown overflow policy: %q", policy)
+	}
+
+	return &IngestQueue{
+		size:   size,
+		policy: policy,
+		items:  make([]models.FrigateEvent, 0, size),
+		ready:  make(chan struct{}, 1),
+	}, nil
+}
+
+// Push adds evt, applying the overflow policy if the queue is full
+func (q *IngestQueue) Push(evt models.FrigateEvent) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.items) >= q.size {
+		if !q.overflow(evt) {
+			return
+		}
+	}
+
+	q.items = append(q.items, evt)
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// overflow makes room for evt according to the policy. It returns false if
+// evt was dropped or already merged into the queue. Callers must hold q.mu.
+func (q *IngestQueue) overflow(evt models.FrigateEvent) bool {
+	if q.policy == OverflowCoalesce {
+		for i := len(q.items) - 1; i >= 0; i-- {
+			if q.items[i].After.ID == evt.After.ID && q.items[i].Type != "end" {
+				q.items[i] = evt
+				q.coalesced.Add(1)
+				q.warn()
+				return false
+			}
+		}
+	}
+
+	q.dropped.Add(1)
+	q.warn()
+
+	if q.policy == OverflowDropNewest {
+		return false
+	}
+
+	q.items = q.items[1:]
+	return true
+}
+
+func (q *IngestQueue) warn() {
+	if time.Since(q.lastWarn) < overflowWarnInterval {
+		return
+	}
+	q.lastWarn = time.Now()
+	stats := q.Stats()
+	logger.Warnf("Ingest queue full (%d messages, policy %s): %d dropped, %d coalesced so far",
+		q.size, q.policy, stats.Dropped, stats.Coalesced)
+}
+
+// Forward moves queued events to out in order, blocking on out as needed. It never returns.
+func (q *IngestQueue) Forward(out chan<- models.FrigateEvent) {
+	for {
+		<-q.ready
+
+		for {
+			q.mu.Lock()
+			if len(q.items) == 0 {
+				q.mu.Unlock()
+				break
+			}
+			evt := q.items[0]
+			q.items = q.items[1:]
+			q.mu.Unlock()
+
+			out <- evt
+		}
+	}
+}
+
+// Len returns the number of queued events
+func (q *IngestQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+func (q *IngestQueue) Stats() QueueStats {
+	return QueueStats{
+		Dropped:   q.dropped.Load(),
+		Coalesced: q.coalesced.Load(),
+	}
+}
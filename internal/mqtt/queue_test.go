@@ -0,0 +1,126 @@
+package mqtt
+
+import (
+	"testing"
+	"time"
+
+	"frigate-custom-reviews/internal/models"
+)
+
+func event(id, msgType string, frameTime float64) models.FrigateEvent {
+	return models.FrigateEvent{Type: msgType, After: models.FrigateEventState{ID: id, FrameTime: frameTime}}
+}
+
+func drain(q *IngestQueue) []models.FrigateEvent {
+	var out []models.FrigateEvent
+	for q.Len() > 0 {
+		q.mu.Lock()
+		out = append(out, q.items[0])
+		q.items = q.items[1:]
+		q.mu.Unlock()
+	}
+	return out
+}
+
+func TestIngestQueue_Policies(t *testing.T) {
+	tests := []struct {
+		policy    string
+		want      []float64 // Frame times left in the queue
+		dropped   int64
+		coalesced int64
+	}{
+		{policy: OverflowDropOldest, want: []float64{2, 3, 4}, dropped: 1},
+		{policy: OverflowDropNewest, want: []float64{1, 2, 3}, dropped: 1},
+		{policy: OverflowCoalesce, want: []float64{1, 4, 3}, coalesced: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			q, err := NewIngestQueue(3, tt.policy)
+			if err != nil {
+				t.Fatalf("NewIngestQueue: %v", err)
+			}
+
+			q.Push(event("a", "new", 1))
+			q.Push(event("b", "new", 2))
+			q.Push(event("c", "new", 3))
+			q.Push(event("b", "update", 4)) // Overflows
+
+			var got []float64
+			for _, evt := range drain(q) {
+				got = append(got, evt.After.FrameTime)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Queue = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("Queue = %v, want %v", got, tt.want)
+				}
+			}
+
+			stats := q.Stats()
+			if stats.Dropped != tt.dropped || stats.Coalesced != tt.coalesced {
+				t.Errorf("Stats = %+v, want dropped %d coalesced %d", stats, tt.dropped, tt.coalesced)
+			}
+		})
+	}
+}
+
+func TestIngestQueue_CoalesceKeepsEnd(t *testing.T) {
+	q, _ := NewIngestQueue(2, OverflowCoalesce)
+	q.Push(event("a", "end", 1))
+	q.Push(event("b", "new", 2))
+	q.Push(event("a", "update", 3)) // Must not replace the queued end; falls back to drop oldest
+
+	got := drain(q)
+	if len(got) != 2 || got[0].After.ID != "b" || got[1].After.FrameTime != 3 {
+		t.Errorf("Unexpected queue: %+v", got)
+	}
+}
+
+func TestIngestQueue_PushNeverBlocks(t *testing.T) {
+	q, _ := NewIngestQueue(10, OverflowDropOldest)
+	out := make(chan models.FrigateEvent) // Unbuffered and never read: a stalled engine
+	go q.Forward(out)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 1000; i++ {
+			q.Push(event("a", "update", float64(i)))
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Push blocked while the consumer was stalled")
+	}
+
+	// Once the engine catches up it receives the newest events
+	received := 0
+	for {
+		select {
+		case evt := <-out:
+			received++
+			if evt.After.FrameTime == 999 {
+				if received > 11 {
+					t.Errorf("Expected at most 11 events after overflow, got %d", received)
+				}
+				return
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Newest event was never forwarded")
+		}
+	}
+}
+
+func TestNewIngestQueue_Validates(t *testing.T) {
+	if _, err := NewIngestQueue(0, OverflowDropOldest); err == nil {
+		t.Error("Expected error for zero size")
+	}
+	if _, err := NewIngestQueue(10, "block"); err == nil {
+		t.Error("Expected error for unknown policy")
+	}
+}
@@ -0,0 +1,65 @@
+package reconcile
+
+import (
+	"sort"
+	"time"
+
+	"frigate-custom-reviews/internal/frigate"
+	"frigate-custom-reviews/internal/models"
+)
+
+// backfillLookback is how long before the backfill window an ended event
+// may have started and still be found. The API filters by start time, so
+// longer events that ended within the window are missed.
+const backfillLookback = 24 * time.Hour
+
+// EventLister queries past events (see frigate.Client)
+type EventLister interface {
+	GetEvents(q frigate.EventQuery) ([]models.FrigateEventState, error)
+}
+
+// StartupEvents returns the messages that rebuild the engine's reviews
+// after a restart: events of the named source still in progress and events
+// that ended within window before now, ordered by start time. window should
+// be the largest profile gap, so reviews that were waiting out their gap
+// are restored; Engine.Recover leaves out profiles with a shorter gap.
+func StartupEvents(name string, lister EventLister, now time.Time, window time.Duration) ([]models.FrigateEvent, error) {
+	states, err := lister.GetEvents(frigate.EventQuery{InProgress: true})
+	if err != nil {
+		return nil, err
+	}
+
+	if window > 0 {
+		since := now.Add(-window)
+		recent, err := lister.GetEvents(frigate.EventQuery{After: float64(since.Add(-backfillLookback).Unix())})
+		if err != nil {
+			return nil, err
+		}
+		for _, state := range recent {
+			if state.EndTime != 0 && state.EndTime >= float64(since.Unix()) {
+				states = append(states, state)
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	var events []models.FrigateEvent
+	for _, state := range states {
+		if seen[state.ID] {
+			continue
+		}
+		seen[state.ID] = true
+		state.Source = name
+
+		msgType := "update"
+		if state.EndTime != 0 {
+			msgType = "end"
+		}
+		events = append(events, models.FrigateEvent{Type: msgType, After: state})
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].After.StartTime < events[j].After.StartTime
+	})
+	return events, nil
+}
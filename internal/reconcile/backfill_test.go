@@ -0,0 +1,70 @@
+package reconcile
+
+import (
+	"testing"
+	"time"
+
+	"frigate-custom-reviews/internal/frigate"
+	"frigate-custom-reviews/internal/models"
+)
+
+type fakeLister struct {
+	events  []models.FrigateEventState
+	queries []frigate.EventQuery
+}
+
+func (f *fakeLister) GetEvents(q frigate.EventQuery) ([]models.FrigateEventState, error) {
+	f.queries = append(f.queries, q)
+	var out []models.FrigateEventState
+	for _, e := range f.events {
+		if q.InProgress && e.EndTime != 0 {
+			continue
+		}
+		if q.After > 0 && e.StartTime < q.After {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+func TestStartupEvents(t *testing.T) {
+	now := time.Unix(100000, 0)
+	lister := &fakeLister{events: []models.FrigateEventState{
+		{ID: "long-running", StartTime: 1000},                   // In progress, started long ago
+		{ID: "recent-end", StartTime: 99900, EndTime: 99950},    // Ended within the 120s window
+		{ID: "long-end", StartTime: 97000, EndTime: 99980},      // Started before the window, ended inside it
+		{ID: "old-end", StartTime: 99000, EndTime: 99500},       // Ended before the window
+		{ID: "active", StartTime: 99990},                        // In progress, returned by both queries
+		{ID: "day-long-end", StartTime: 20000, EndTime: 99990},  // Started hours before the window, ended inside it
+		{ID: "ancient-end", StartTime: 10000, EndTime: 99990.5}, // Started before the lookback, can't be found
+	}}
+
+	events, err := StartupEvents("garage", lister, now, 120*time.Second)
+	if err != nil {
+		t.Fatalf("StartupEvents: %v", err)
+	}
+
+	want := []struct{ id, typ string }{
+		{"long-running", "update"},
+		{"day-long-end", "end"},
+		{"long-end", "end"},
+		{"recent-end", "end"},
+		{"active", "update"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("Expected %d events, got %+v", len(want), events)
+	}
+	for i, w := range want {
+		if events[i].After.ID != w.id || events[i].Type != w.typ || events[i].After.Source != "garage" {
+			t.Errorf("Event %d = %s (%s), want %s (%s)", i, events[i].After.ID, events[i].Type, w.id, w.typ)
+		}
+	}
+
+	// Without a window only in-progress events are recovered
+	lister.queries = nil
+	events, _ = StartupEvents("garage", lister, now, 0)
+	if len(events) != 2 || len(lister.queries) != 1 {
+		t.Errorf("Expected only in-progress events, got %d events from %d queries", len(events), len(lister.queries))
+	}
+}
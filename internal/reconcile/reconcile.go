@@ -0,0 +1,167 @@
+// Package reconcile brings the engine's view of in-progress Frigate events
+// back in line with the Frigate API after messages may have been missed.
+package reconcile
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"frigate-custom-reviews/internal/clock"
+	"frigate-custom-reviews/internal/frigate"
+	"frigate-custom-reviews/internal/logger"
+	"frigate-custom-reviews/internal/models"
+)
+
+// Source queries the Frigate events API (see frigate.Client). GetEvent
+// returns an error wrapping frigate.ErrNotFound for deleted events.
+type Source interface {
+	GetActiveEvents() ([]models.FrigateEvent, error)
+	GetEvent(id string) (models.FrigateEventState, error)
+}
+
+// Tracker exposes the events tracked in active reviews (see engine.Engine)
+type Tracker interface {
+	ActiveEvents() []models.FrigateEventState
+}
+
+// Result counts the synthetic messages pushed by a reconciliation
+type Result struct {
+	Ended   int // Tracked events Frigate no longer reports in progress
+	Started int // In-progress events that were not tracked yet
+}
+
+// Reconciler checks the tracked events of one Frigate source
+type Reconciler struct {
+	name    string // Source name, events of other sources are left alone
+	source  Source
+	tracker Tracker
+	ingest  chan<- models.FrigateEvent
+	clock   clock.Clock
+}
+
+// New returns a Reconciler for the source called name, feeding its
+// synthetic events into ingest, which should be the tracker's ingest
+// channel. clk stamps end times.
+func New(name string, source Source, tracker Tracker, ingest chan<- models.FrigateEvent, clk clock.Clock) *Reconciler {
+	return &Reconciler{name: name, source: source, tracker: tracker, ingest: ingest, clock: clk}
+}
+
+// trackedEvents returns the tracker's active events from this source.
+// Review segments are left out, the events API doesn't know them.
+func (r *Reconciler) trackedEvents() []models.FrigateEventState {
+	var states []models.FrigateEventState
+	for _, state := range r.tracker.ActiveEvents() {
+		if state.Source == r.name && !state.IsReviewSegment() {
+			states = append(states, state)
+		}
+	}
+	return states
+}
+
+// Run compares the tracked events with Frigate's in-progress list. Tracked
+// events missing from the list ended while we weren't listening and get an
+// 'end' message, with Frigate's end time if it can be looked up and now
+// otherwise. Unknown in-progress events are fed in as updates.
+func (r *Reconciler) Run() (Result, error) {
+	var result Result
+
+	inProgress, err := r.source.GetActiveEvents()
+	if err != nil {
+		return result, fmt.Errorf("failed to list in-progress events: %w", err)
+	}
+
+	active := make(map[string]bool, len(inProgress))
+	for _, evt := range inProgress {
+		active[evt.After.ID] = true
+	}
+
+	tracked := make(map[string]bool)
+	for _, state := range r.trackedEvents() {
+		tracked[state.ID] = true
+		if active[state.ID] {
+			continue
+		}
+
+		endTime, err := r.lookupEndTime(state.ID)
+		if err != nil && !errors.Is(err, frigate.ErrNotFound) {
+			logger.Warnf("Reconcile: failed to look up event %s, ending it now: %v", state.ID, err)
+		}
+		if endTime == 0 {
+			endTime = float64(r.clock.Now().Unix())
+		}
+		r.end(state, endTime)
+		result.Ended++
+	}
+
+	for _, evt := range inProgress {
+		if tracked[evt.After.ID] {
+			continue
+		}
+		evt.After.Source = r.name
+		r.ingest <- evt
+		result.Started++
+	}
+
+	return result, nil
+}
+
+// CheckTracked asks the API for the status of every tracked event and ends
+// those that have ended (with Frigate's end time) or were deleted. Lookup
+// failures leave the event to the next check or the ghost timeout.
+func (r *Reconciler) CheckTracked() Result {
+	var result Result
+
+	for _, state := range r.trackedEvents() {
+		endTime, err := r.lookupEndTime(state.ID)
+		if errors.Is(err, frigate.ErrNotFound) {
+			endTime = float64(r.clock.Now().Unix())
+		} else if err != nil {
+			logger.Debugf("Reconcile: failed to look up event %s: %v", state.ID, err)
+			continue
+		}
+		if endTime == 0 {
+			continue
+		}
+		r.end(state, endTime)
+		result.Ended++
+	}
+
+	return result
+}
+
+// RunEvery calls CheckTracked every interval until done is closed
+func (r *Reconciler) RunEvery(interval time.Duration, done <-chan struct{}) {
+	ticker := r.clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C():
+			if result := r.CheckTracked(); result.Ended > 0 {
+				logger.Infof("Reconcile: ended %d events missed over MQTT", result.Ended)
+			}
+		}
+	}
+}
+
+// lookupEndTime returns the event's end time per the API, 0 if it is still
+// in progress
+func (r *Reconciler) lookupEndTime(id string) (float64, error) {
+	current, err := r.source.GetEvent(id)
+	if err != nil {
+		return 0, err
+	}
+	return current.EndTime, nil
+}
+
+// end pushes an 'end' message for a tracked event. The last known state is
+// kept so the event still matches its profiles.
+func (r *Reconciler) end(state models.FrigateEventState, endTime float64) {
+	ended := state
+	ended.EndTime = endTime
+	r.ingest <- models.FrigateEvent{Type: "end", Before: state, After: ended}
+	logger.Infof("Reconcile: event %s (%s) is no longer in progress, ending it", state.ID, state.Camera)
+}
@@ -0,0 +1,144 @@
+package reconcile
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"frigate-custom-reviews/internal/clock"
+	"frigate-custom-reviews/internal/frigate"
+	"frigate-custom-reviews/internal/models"
+)
+
+type fakeSource struct {
+	events []models.FrigateEvent
+	err    error
+	lookup map[string]models.FrigateEventState // Missing IDs are not found
+}
+
+func (f *fakeSource) GetActiveEvents() ([]models.FrigateEvent, error) {
+	return f.events, f.err
+}
+
+func (f *fakeSource) GetEvent(id string) (models.FrigateEventState, error) {
+	if f.err != nil {
+		return models.FrigateEventState{}, f.err
+	}
+	state, ok := f.lookup[id]
+	if !ok {
+		return models.FrigateEventState{}, fmt.Errorf("%s: %w", id, frigate.ErrNotFound)
+	}
+	return state, nil
+}
+
+// drain returns the events pushed so far
+func drain(ingest chan models.FrigateEvent) []models.FrigateEvent {
+	var pushed []models.FrigateEvent
+	for len(ingest) > 0 {
+		pushed = append(pushed, <-ingest)
+	}
+	return pushed
+}
+
+type fakeTracker []models.FrigateEventState
+
+func (f fakeTracker) ActiveEvents() []models.FrigateEventState {
+	return f
+}
+
+func TestRun_EndsMissingAndAddsUnknownEvents(t *testing.T) {
+	source := &fakeSource{
+		events: []models.FrigateEvent{
+			{Type: "update", After: models.FrigateEventState{ID: "still", Camera: "cam1"}},
+			{Type: "update", After: models.FrigateEventState{ID: "started", Camera: "cam2"}},
+		},
+		lookup: map[string]models.FrigateEventState{
+			"finished": {ID: "finished", EndTime: 1500},
+		},
+	}
+	tracker := fakeTracker{
+		{ID: "still", Camera: "cam1", Label: "person"},
+		{ID: "gone", Camera: "cam1", Label: "person", EnteredZones: []string{"porch"}},
+		{ID: "finished", Camera: "cam1", Label: "person"},
+	}
+	ingest := make(chan models.FrigateEvent, 10)
+	clk := clock.NewVirtual(time.Unix(2000, 0))
+
+	result, err := New("", source, tracker, ingest, clk).Run()
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if result.Ended != 2 || result.Started != 1 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	pushed := drain(ingest)
+	if len(pushed) != 3 {
+		t.Fatalf("Expected 3 synthetic events, got %d", len(pushed))
+	}
+
+	// Deleted from Frigate, so the end time falls back to now
+	gone := pushed[0]
+	if gone.Type != "end" || gone.After.ID != "gone" || gone.After.EndTime != 2000 {
+		t.Errorf("Unexpected end event: %+v", gone)
+	}
+	if gone.After.Label != "person" || len(gone.After.EnteredZones) != 1 {
+		t.Errorf("End event lost the tracked state: %+v", gone.After)
+	}
+	if finished := pushed[1]; finished.After.ID != "finished" || finished.After.EndTime != 1500 {
+		t.Errorf("Expected Frigate's end time for finished, got %+v", finished.After)
+	}
+	if pushed[2].After.ID != "started" {
+		t.Errorf("Expected the untracked in-progress event, got %s", pushed[2].After.ID)
+	}
+}
+
+func TestCheckTracked_AppliesRealEndTimes(t *testing.T) {
+	source := &fakeSource{lookup: map[string]models.FrigateEventState{
+		"running":  {ID: "running"},
+		"finished": {ID: "finished", EndTime: 1500.5},
+	}}
+	tracker := fakeTracker{
+		{ID: "running", Camera: "cam1"},
+		{ID: "finished", Camera: "cam1", Label: "car"},
+		{ID: "deleted", Camera: "cam2"},
+		{ID: "other", Camera: "cam1", Source: "house"}, // Another source's reconciler handles it
+	}
+	ingest := make(chan models.FrigateEvent, 10)
+	clk := clock.NewVirtual(time.Unix(2000, 0))
+
+	result := New("", source, tracker, ingest, clk).CheckTracked()
+	if result.Ended != 2 {
+		t.Errorf("Expected 2 events ended, got %+v", result)
+	}
+
+	pushed := drain(ingest)
+	if len(pushed) != 2 {
+		t.Fatalf("Expected 2 end events, got %d", len(pushed))
+	}
+	if pushed[0].After.ID != "finished" || pushed[0].After.EndTime != 1500.5 || pushed[0].After.Label != "car" {
+		t.Errorf("Unexpected end for finished: %+v", pushed[0].After)
+	}
+	if pushed[1].After.ID != "deleted" || pushed[1].After.EndTime != 2000 {
+		t.Errorf("Unexpected end for deleted: %+v", pushed[1].After)
+	}
+
+	// API failures leave events alone
+	source.err = errors.New("timeout")
+	if result := New("", source, tracker, ingest, clk).CheckTracked(); result.Ended != 0 || len(ingest) != 0 {
+		t.Errorf("Expected nothing ended on API errors, got %+v", result)
+	}
+}
+
+func TestRun_APIErrorChangesNothing(t *testing.T) {
+	source := &fakeSource{err: errors.New("connection refused")}
+	ingest := make(chan models.FrigateEvent, 10)
+
+	if _, err := New("", source, fakeTracker{{ID: "a"}}, ingest, clock.Real()).Run(); err == nil {
+		t.Fatal("Expected an error")
+	}
+	if len(ingest) != 0 {
+		t.Errorf("Expected no events to be pushed on error, got %d", len(ingest))
+	}
+}
@@ -0,0 +1,276 @@
+package replay
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"frigate-custom-reviews/internal/clock"
+	"frigate-custom-reviews/internal/engine"
+	"frigate-custom-reviews/internal/logger"
+	"frigate-custom-reviews/internal/models"
+)
+
+// Record is one captured MQTT message: the raw payload and when it was received
+type Record struct {
+	Time    float64         `json:"ts"` // Unix seconds
+	Topic   string          `json:"topic"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+func (r Record) Timestamp() time.Time {
+	sec, frac := math.Modf(r.Time)
+	return time.Unix(int64(sec), int64(frac*1e9))
+}
+
+// Writer appends records in the format read by Read
+type Writer struct {
+	w io.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+func (w *Writer) Write(rec Record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal record: %w", err)
+	}
+	_, err = w.w.Write(append(data, '\n'))
+	return err
+}
+
+// ReadFile parses a newline-delimited JSON capture file
+func ReadFile(path string) ([]Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open capture: %w", err)
+	}
+	defer f.Close()
+
+	return Read(f)
+}
+
+// Read parses newline-delimited JSON records, skipping blank lines, and
+// returns them in timestamp order.
+func Read(r io.Reader) ([]Record, error) {
+	var records []Record
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read capture: %w", err)
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Time < records[j].Time
+	})
+
+	return records, nil
+}
+
+// FromEvents converts event states, e.g. from the Frigate API, into records
+// on topic: a 'new' at each event's start and an 'end' at its end, if it
+// has ended. The whole state (zones included) is known up front, so the
+// 'new' already carries it, with the object in every entered zone until the
+// end. Records are returned in timestamp order.
+func FromEvents(states []models.FrigateEventState, topic string) ([]Record, error) {
+	var records []Record
+
+	add := func(ts float64, evt models.FrigateEvent) error {
+		payload, err := json.Marshal(evt)
+		if err != nil {
+			return fmt.Errorf("failed to marshal event %s: %w", evt.After.ID, err)
+		}
+		records = append(records, Record{Time: ts, Topic: topic, Payload: payload})
+		return nil
+	}
+
+	for _, state := range states {
+		started := state
+		started.EndTime = 0
+		started.FrameTime = state.StartTime
+		if len(started.CurrentZones) == 0 {
+			started.CurrentZones = state.EnteredZones
+		}
+		if err := add(state.StartTime, models.FrigateEvent{Type: "new", After: started}); err != nil {
+			return nil, err
+		}
+
+		if state.EndTime == 0 {
+			continue
+		}
+		ended := state
+		ended.FrameTime = state.EndTime
+		if err := add(state.EndTime, models.FrigateEvent{Type: "end", Before: started, After: ended}); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Time < records[j].Time
+	})
+	return records, nil
+}
+
+type Options struct {
+	Speed        float64       // Real-time multiplier, 0 replays as fast as possible
+	TickInterval time.Duration // Virtual time between engine ticks, default eng.TickInterval()
+	MaxDrain     time.Duration // Virtual time to keep ticking after the last record, default 1h
+	// Sources names the Frigate source of each record topic, for events
+	// captured from MQTT that don't carry one
+	Sources map[string]string
+	// ReviewTopics are the record topics carrying frigate/reviews payloads
+	ReviewTopics map[string]bool
+}
+
+type Stats struct {
+	Records int
+	Events  int
+	Skipped int
+}
+
+// Run feeds records through eng, moving clk to each record's timestamp and
+// ticking the engine in between as the live loop would. After the last
+// record it keeps ticking until every review has closed or MaxDrain passes.
+// eng must have been created with engine.WithClock(clk).
+func Run(eng *engine.Engine, clk *clock.Virtual, records []Record, opts Options) Stats {
+	if opts.TickInterval <= 0 {
+		opts.TickInterval = eng.TickInterval()
+	}
+	if opts.MaxDrain <= 0 {
+		opts.MaxDrain = time.Hour
+	}
+
+	d := &driver{eng: eng, clk: clk, opts: opts}
+	d.nextTick = clk.Now().Add(opts.TickInterval)
+
+	var stats Stats
+	for _, rec := range records {
+		stats.Records++
+		d.tickUntil(rec.Timestamp())
+		d.advance(rec.Timestamp())
+
+		evt, err := decode(rec, opts)
+		if err != nil {
+			logger.Warnf("Skipping record at %.3f: %v", rec.Time, err)
+			stats.Skipped++
+			continue
+		}
+
+		if source := opts.Sources[rec.Topic]; source != "" && evt.After.Source == "" {
+			evt.Before.Source = source
+			evt.After.Source = source
+		}
+
+		eng.Step(evt)
+		stats.Events++
+	}
+
+	deadline := clk.Now().Add(opts.MaxDrain)
+	for eng.ActiveReviews() > 0 && d.nextTick.Before(deadline) {
+		d.tick()
+	}
+	if n := eng.ActiveReviews(); n > 0 {
+		logger.Warnf("%d reviews still active after draining for %v", n, opts.MaxDrain)
+	}
+
+	return stats
+}
+
+// decode parses a record as a Frigate event, or as a review segment if it
+// was captured from a reviews topic
+func decode(rec Record, opts Options) (models.FrigateEvent, error) {
+	if opts.ReviewTopics[rec.Topic] {
+		var review models.FrigateReview
+		if err := json.Unmarshal(rec.Payload, &review); err != nil {
+			return models.FrigateEvent{}, err
+		}
+		return review.Event(), nil
+	}
+
+	var evt models.FrigateEvent
+	err := json.Unmarshal(rec.Payload, &evt)
+	return evt, err
+}
+
+type driver struct {
+	eng      *engine.Engine
+	clk      *clock.Virtual
+	opts     Options
+	nextTick time.Time
+}
+
+// tickUntil runs every engine tick scheduled up to and including t
+func (d *driver) tickUntil(t time.Time) {
+	for !d.nextTick.After(t) {
+		d.tick()
+	}
+}
+
+func (d *driver) tick() {
+	d.advance(d.nextTick)
+	d.eng.Tick()
+	d.nextTick = d.nextTick.Add(d.opts.TickInterval)
+}
+
+// advance moves the virtual clock to t, sleeping the scaled difference when
+// replaying at a finite speed.
+func (d *driver) advance(t time.Time) {
+	if d.opts.Speed > 0 {
+		if delta := t.Sub(d.clk.Now()); delta > 0 {
+			time.Sleep(time.Duration(float64(delta) / d.opts.Speed))
+		}
+	}
+	d.clk.Set(t)
+}
+
+// Collector is an engine Publisher that keeps the latest state of every
+// review, in the order reviews were first seen.
+type Collector struct {
+	order  []string
+	states map[string]models.ReviewState
+}
+
+func NewCollector() *Collector {
+	return &Collector{states: make(map[string]models.ReviewState)}
+}
+
+func (c *Collector) Publish(msg models.MessagePayload) {
+	if msg.After == nil {
+		return
+	}
+	if _, seen := c.states[msg.After.ID]; !seen {
+		c.order = append(c.order, msg.After.ID)
+	}
+	c.states[msg.After.ID] = *msg.After
+}
+
+// Reviews returns the final state of each review
+func (c *Collector) Reviews() []models.ReviewState {
+	out := make([]models.ReviewState, 0, len(c.order))
+	for _, id := range c.order {
+		out = append(out, c.states[id])
+	}
+	return out
+}
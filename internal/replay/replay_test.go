@@ -0,0 +1,226 @@
+package replay
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"frigate-custom-reviews/internal/clock"
+	"frigate-custom-reviews/internal/engine"
+	"frigate-custom-reviews/internal/models"
+)
+
+type collector struct {
+	messages []models.MessagePayload
+	times    []time.Time
+	clk      *clock.Virtual
+}
+
+func (c *collector) Publish(msg models.MessagePayload) {
+	c.messages = append(c.messages, msg)
+	c.times = append(c.times, c.clk.Now())
+}
+
+const capture = `{"ts": 1000.5, "topic": "frigate/events", "payload": {"type": "new", "after": {"id": "a", "camera": "cam1", "label": "person", "start_time": 1000}}}
+
+{"ts": 1010.2, "topic": "frigate/events", "payload": {"type": "end", "after": {"id": "a", "camera": "cam1", "label": "person", "start_time": 1000, "end_time": 1010, "entered_zones": ["porch"]}}}
+{"ts": 1003.0, "topic": "frigate/events", "payload": {"type": "update", "after": {"id": "a", "camera": "cam1", "label": "person", "start_time": 1000, "entered_zones": ["porch"]}}}
+`
+
+func TestRead_SortsAndSkipsBlankLines(t *testing.T) {
+	records, err := Read(strings.NewReader(capture))
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 records, got %d", len(records))
+	}
+	if records[1].Time != 1003.0 {
+		t.Errorf("Records not sorted by time: %v", records)
+	}
+}
+
+func TestWriter_RoundTrip(t *testing.T) {
+	var buf strings.Builder
+	w := NewWriter(&buf)
+
+	payload := `{"type":"new","after":{"id":"a","camera":"cam1"}}`
+	if err := w.Write(Record{Time: 1000.25, Topic: "frigate/events", Payload: []byte(payload)}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	records, err := Read(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if len(records) != 1 || records[0].Time != 1000.25 || string(records[0].Payload) != payload {
+		t.Errorf("Round trip mismatch: %+v", records)
+	}
+	if got := records[0].Timestamp().UnixMilli(); got != 1000250 {
+		t.Errorf("Timestamp = %d ms, want 1000250", got)
+	}
+}
+
+func TestRun_ClosesReviewAfterVirtualGap(t *testing.T) {
+	records, err := Read(strings.NewReader(capture))
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+
+	clk := clock.NewVirtual(records[0].Timestamp())
+	out := &collector{clk: clk}
+	profile := models.Profile{Name: "front", Cameras: []string{"cam1"}, Gap: 30}
+	eng := engine.NewEngine([]models.Profile{profile}, out, engine.WithClock(clk), engine.WithPublishUpdates(true))
+
+	stats := Run(eng, clk, records, Options{})
+	if stats.Events != 3 || stats.Skipped != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	var types []string
+	for _, msg := range out.messages {
+		types = append(types, msg.Type)
+	}
+	if strings.Join(types, ",") != "new,update,update,end" {
+		t.Fatalf("Unexpected message sequence: %v", types)
+	}
+
+	// The event ended at 1010 and the gap is 30s, so the review closes on the first tick after 1040
+	ended := out.times[len(out.times)-1].Unix()
+	if ended < 1040 || ended > 1042 {
+		t.Errorf("Review ended at %d, expected shortly after 1040", ended)
+	}
+}
+
+func TestFromEvents_BuildsChronologicalCapture(t *testing.T) {
+	states := []models.FrigateEventState{
+		{ID: "b", Camera: "cam1", Label: "car", StartTime: 1005, EndTime: 1020, EnteredZones: []string{"street"}},
+		{ID: "a", Camera: "cam1", Label: "person", StartTime: 1000, EndTime: 1010},
+		{ID: "c", Camera: "cam1", Label: "person", StartTime: 1030}, // Still in progress
+	}
+
+	records, err := FromEvents(states, "frigate/events")
+	if err != nil {
+		t.Fatalf("FromEvents: %v", err)
+	}
+
+	var got []string
+	for _, rec := range records {
+		var evt models.FrigateEvent
+		if err := json.Unmarshal(rec.Payload, &evt); err != nil {
+			t.Fatalf("Invalid payload: %v", err)
+		}
+		got = append(got, evt.After.ID+":"+evt.Type)
+
+		if evt.Type == "new" && evt.After.EndTime != 0 {
+			t.Errorf("'new' for %s carries an end time", evt.After.ID)
+		}
+		if evt.After.ID == "b" && len(evt.After.EnteredZones) != 1 {
+			t.Errorf("Zones missing from %s '%s'", evt.After.ID, evt.Type)
+		}
+	}
+	if strings.Join(got, ",") != "a:new,b:new,a:end,b:end,c:new" {
+		t.Errorf("Unexpected records %v", got)
+	}
+
+	// The capture replays into one review
+	clk := clock.NewVirtual(records[0].Timestamp())
+	out := &collector{clk: clk}
+	eng := engine.NewEngine([]models.Profile{{Name: "all", Cameras: []string{"cam1"}, Gap: 30}}, out, engine.WithClock(clk))
+	Run(eng, clk, records[:4], Options{})
+	if len(out.messages) != 2 || out.messages[1].Type != models.MessageEnd || out.messages[1].After.EventCount != 2 {
+		t.Errorf("Unexpected replay of backfilled events: %+v", out.messages)
+	}
+}
+
+func TestRun_TagsSourceByTopic(t *testing.T) {
+	const multi = `{"ts": 1000, "topic": "house/events", "payload": {"type": "new", "after": {"id": "a", "camera": "driveway", "label": "car", "start_time": 1000}}}
+{"ts": 1001, "topic": "garage/events", "payload": {"type": "new", "after": {"id": "b", "camera": "driveway", "label": "car", "start_time": 1001}}}
+`
+	records, err := Read(strings.NewReader(multi))
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+
+	clk := clock.NewVirtual(records[0].Timestamp())
+	out := &collector{clk: clk}
+	profile := models.Profile{Name: "garage", Cameras: []string{"garage/driveway"}, Gap: 30}
+	eng := engine.NewEngine([]models.Profile{profile}, out, engine.WithClock(clk))
+
+	Run(eng, clk, records, Options{Sources: map[string]string{"house/events": "house", "garage/events": "garage"}})
+
+	if len(out.messages) == 0 || out.messages[0].Type != models.MessageNew {
+		t.Fatalf("Expected a review for the garage event, got %+v", out.messages)
+	}
+	if got := out.messages[0].After.Cameras; len(got) != 1 || got[0] != "garage/driveway" {
+		t.Errorf("Review cameras = %v, want [garage/driveway]", got)
+	}
+	for _, msg := range out.messages {
+		for _, ev := range msg.After.LinkedEvents {
+			if ev.ID != "b" {
+				t.Errorf("Review linked event %s from the wrong source", ev.ID)
+			}
+		}
+	}
+}
+
+func TestRun_DecodesReviewTopics(t *testing.T) {
+	const reviews = `{"ts": 1000, "topic": "frigate/reviews", "payload": {"type": "new", "after": {"id": "seg1", "camera": "cam1", "start_time": 1000, "severity": "alert", "data": {"objects": ["person"]}}}}
+{"ts": 1010, "topic": "frigate/reviews", "payload": {"type": "end", "after": {"id": "seg1", "camera": "cam1", "start_time": 1000, "end_time": 1010, "severity": "alert", "data": {"objects": ["person"]}}}}
+`
+	records, err := Read(strings.NewReader(reviews))
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+
+	clk := clock.NewVirtual(records[0].Timestamp())
+	out := &collector{clk: clk}
+	profile := models.Profile{Name: "alerts", Input: models.InputReviews, Severities: []string{models.SeverityAlert}, Gap: 5}
+	eng := engine.NewEngine([]models.Profile{profile}, out, engine.WithClock(clk))
+
+	stats := Run(eng, clk, records, Options{ReviewTopics: map[string]bool{"frigate/reviews": true}})
+	if stats.Skipped != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if len(out.messages) != 2 || out.messages[1].Type != models.MessageEnd {
+		t.Fatalf("Expected new and end, got %+v", out.messages)
+	}
+	if got := out.messages[1].After; got.Severity != models.SeverityAlert || got.LinkedEvents[0].ID != "seg1" {
+		t.Errorf("Unexpected review %+v", got)
+	}
+}
+
+func TestFromEvents_SupportsCurrentZonesAndDwell(t *testing.T) {
+	states := []models.FrigateEventState{
+		{ID: "a", Camera: "cam1", Label: "person", StartTime: 1000, EndTime: 1020, EnteredZones: []string{"porch"}},
+		{ID: "b", Camera: "cam1", Label: "person", StartTime: 1100, EndTime: 1102, EnteredZones: []string{"porch"}},
+	}
+	records, err := FromEvents(states, "frigate/events")
+	if err != nil {
+		t.Fatalf("FromEvents: %v", err)
+	}
+
+	clk := clock.NewVirtual(records[0].Timestamp())
+	out := &collector{clk: clk}
+	profile := models.Profile{
+		Name:          "porch",
+		RequiredZones: []string{"porch"},
+		ZoneMode:      models.ZoneModeCurrent,
+		MinDwell:      5,
+		Gap:           10,
+	}
+	eng := engine.NewEngine([]models.Profile{profile}, out, engine.WithClock(clk))
+	Run(eng, clk, records, Options{})
+
+	// a stayed 20s and qualifies; b passed through in 2s
+	var reviews []string
+	for _, msg := range out.messages {
+		if msg.Type == models.MessageNew {
+			reviews = append(reviews, msg.After.LinkedEvents[0].ID)
+		}
+	}
+	if strings.Join(reviews, ",") != "a" {
+		t.Errorf("Expected one review for a, got %v", reviews)
+	}
+}
@@ -0,0 +1,125 @@
+package rotate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Writer is an append-only file writer that rotates by size. When a write
+// would exceed MaxBytes, path is renamed to path.1 (shifting older backups
+// up to path.MaxBackups) and a fresh file is started. Writes are never split
+// across files, so line-oriented formats stay intact. If rotation fails, the
+// write still goes to path and the error is returned with it.
+type Writer struct {
+	path       string
+	maxBytes   int64
+	maxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewWriter opens (or creates) path for appending. A maxBytes of 0 disables rotation.
+func NewWriter(path string, maxBytes int64, maxBackups int) (*Writer, error) {
+	w := &Writer{
+		path:       path,
+		maxBytes:   maxBytes,
+		maxBackups: maxBackups,
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return 0, fmt.Errorf("write to closed file: %s", w.path)
+	}
+
+	var rotateErr error
+	if w.maxBytes > 0 && w.size > 0 && w.size+int64(len(p)) > w.maxBytes {
+		if rotateErr = w.rotate(); rotateErr != nil && w.file == nil {
+			return 0, rotateErr
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	if err != nil {
+		return n, err
+	}
+	return n, rotateErr
+}
+
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+func (w *Writer) open() error {
+	f, err := os.OpenFile(w.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", w.path, err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to stat %s: %w", w.path, err)
+	}
+
+	w.file = f
+	w.size = info.Size()
+	return nil
+}
+
+// rotate moves path aside and opens a fresh file. If that fails, path is
+// reopened for appending so writes continue, and the error is returned.
+func (w *Writer) rotate() error {
+	closeErr := w.file.Close()
+	w.file = nil
+
+	var err error
+	if closeErr != nil {
+		err = fmt.Errorf("failed to close %s: %w", w.path, closeErr)
+	} else {
+		err = w.shift()
+	}
+	if openErr := w.open(); openErr != nil {
+		return errors.Join(err, openErr)
+	}
+	return err
+}
+
+// shift renames path and its backups up by one, or removes path without backups
+func (w *Writer) shift() error {
+	if w.maxBackups > 0 {
+		// Shift path.N-1 -> path.N, ..., path -> path.1; the oldest falls off
+		for i := w.maxBackups - 1; i >= 1; i-- {
+			src := fmt.Sprintf("%s.%d", w.path, i)
+			if _, err := os.Stat(src); err == nil {
+				os.Rename(src, fmt.Sprintf("%s.%d", w.path, i+1))
+			}
+		}
+		if err := os.Rename(w.path, w.path+".1"); err != nil {
+			return fmt.Errorf("failed to rotate %s: %w", w.path, err)
+		}
+	} else if err := os.Remove(w.path); err != nil {
+		return fmt.Errorf("failed to truncate %s: %w", w.path, err)
+	}
+	return nil
+}
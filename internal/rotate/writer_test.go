@@ -0,0 +1,97 @@
+package rotate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	return string(data)
+}
+
+func TestWriter_Rotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	w, err := NewWriter(path, 10, 2)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	defer w.Close()
+
+	for _, line := range []string{"aaaaaa\n", "bbbbbb\n", "cccccc\n", "dddddd\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+
+	// Each line exceeds half the limit, so every write lands in a fresh file
+	if got := readFile(t, path); got != "dddddd\n" {
+		t.Errorf("current = %q", got)
+	}
+	if got := readFile(t, path+".1"); got != "cccccc\n" {
+		t.Errorf("backup 1 = %q", got)
+	}
+	if got := readFile(t, path+".2"); got != "bbbbbb\n" {
+		t.Errorf("backup 2 = %q", got)
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected only 2 backups, found %s.3", path)
+	}
+}
+
+func TestWriter_AppendsToExisting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	if err := os.WriteFile(path, []byte("old\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := NewWriter(path, 0, 0)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	w.Write([]byte(strings.Repeat("x", 100) + "\n"))
+	w.Close()
+
+	if got := readFile(t, path); !strings.HasPrefix(got, "old\nxxx") {
+		t.Errorf("expected append without rotation, got %q", got)
+	}
+}
+
+func TestWriter_KeepsWritingWhenRotationFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	// A directory in the backup's place makes the rename fail
+	if err := os.Mkdir(path+".1", 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := NewWriter(path, 10, 1)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	defer w.Close()
+
+	w.Write([]byte("aaaaaa\n"))
+	if _, err := w.Write([]byte("bbbbbb\n")); err == nil {
+		t.Error("Expected the failed rotation to be reported")
+	}
+
+	// Writes continue in the current file, and rotate once the way is clear
+	os.Remove(path + ".1")
+	if _, err := w.Write([]byte("cccccc\n")); err != nil {
+		t.Fatalf("Write after the failed rotation: %v", err)
+	}
+	if got := readFile(t, path+".1"); got != "aaaaaa\nbbbbbb\n" {
+		t.Errorf("backup 1 = %q", got)
+	}
+	if got := readFile(t, path); got != "cccccc\n" {
+		t.Errorf("current = %q", got)
+	}
+}
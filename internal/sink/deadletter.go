@@ -0,0 +1,61 @@
+package sink
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"frigate-custom-reviews/internal/models"
+)
+
+// DeadLetter is a single undeliverable message as written to the log
+type DeadLetter struct {
+	Time     time.Time             `json:"time"`
+	Webhook  string                `json:"webhook"`
+	Attempts int                   `json:"attempts"`
+	Error    string                `json:"error"`
+	Message  models.MessagePayload `json:"message"`
+	Body     string                `json:"body,omitempty"`
+}
+
+// DeadLetterLog appends undeliverable messages to a newline-delimited JSON file
+type DeadLetterLog struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewDeadLetterLog(path string) *DeadLetterLog {
+	return &DeadLetterLog{path: path}
+}
+
+func (d *DeadLetterLog) Write(webhook string, msg models.MessagePayload, body []byte, attempts int, cause error) error {
+	entry := DeadLetter{
+		Time:     time.Now(),
+		Webhook:  webhook,
+		Attempts: attempts,
+		Message:  msg,
+		Body:     string(body),
+	}
+	if cause != nil {
+		entry.Error = cause.Error()
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letter: %w", err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	f, err := os.OpenFile(d.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open dead letter file: %w", err)
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,56 @@
+package sink
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"frigate-custom-reviews/internal/models"
+)
+
+// File appends each review message as a line of JSON
+type File struct {
+	name   string
+	writer io.Writer
+	closer io.Closer
+}
+
+func NewFile(cfg models.FileOutputConfig) (*File, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("file output has no path")
+	}
+
+	f, err := os.OpenFile(cfg.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open output file: %w", err)
+	}
+
+	return &File{name: "file:" + cfg.Path, writer: f, closer: f}, nil
+}
+
+// NewWriter writes JSON lines to w (e.g. os.Stdout). Closing the sink does not close w.
+func NewWriter(name string, w io.Writer) *File {
+	return &File{name: name, writer: w}
+}
+
+func (f *File) Name() string {
+	return f.name
+}
+
+func (f *File) Send(msg models.MessagePayload) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	_, err = f.writer.Write(append(data, '\n'))
+	return err
+}
+
+func (f *File) Close() error {
+	if f.closer == nil {
+		return nil
+	}
+	return f.closer.Close()
+}
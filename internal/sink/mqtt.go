@@ -0,0 +1,70 @@
+package sink
+
+import (
+	"strings"
+
+	"frigate-custom-reviews/internal/logger"
+	"frigate-custom-reviews/internal/models"
+)
+
+// MQTTPublisher interface to decouple the sink from specific mqtt implementation
+type MQTTPublisher interface {
+	Publish(topic string, qos byte, retained bool, payload interface{}) error
+}
+
+// MQTT publishes review messages to the reviews topic and mirrors the
+// resulting state onto the retained state topic.
+type MQTT struct {
+	client       MQTTPublisher
+	publishTopic string // Template, see expandTopic
+	stateTopic   string // Template, empty disables retained state
+	qos          models.MQTTQoSConfig
+}
+
+func NewMQTT(client MQTTPublisher, cfg models.MQTTConfig) *MQTT {
+	return &MQTT{
+		client:       client,
+		publishTopic: cfg.ReviewsPublishTopic,
+		stateTopic:   cfg.StateTopic,
+		qos:          cfg.QoS,
+	}
+}
+
+func (m *MQTT) Name() string {
+	return "mqtt"
+}
+
+func (m *MQTT) Send(msg models.MessagePayload) error {
+	if err := m.client.Publish(expandTopic(m.publishTopic, msg), m.qos.Reviews, false, msg); err != nil {
+		return err
+	}
+
+	if msg.After == nil {
+		logger.Infof("[MQTT] Published '%s'", msg.Type)
+		return nil
+	}
+
+	if m.stateTopic != "" {
+		if err := m.client.Publish(expandTopic(m.stateTopic, msg), m.qos.State, true, msg.After); err != nil {
+			logger.Warnf("Error publishing review state: %v", err)
+		}
+	}
+
+	logger.Infof("[MQTT] Published '%s' for Review %s (Profile: %s)", msg.Type, msg.After.ID, msg.After.ProfileName)
+	return nil
+}
+
+func (m *MQTT) Close() error {
+	return nil
+}
+
+// expandTopic fills the {profile} and {review_id} placeholders of a topic template
+func expandTopic(template string, msg models.MessagePayload) string {
+	if msg.After == nil {
+		return template
+	}
+	return strings.NewReplacer(
+		"{profile}", msg.After.ProfileName,
+		"{review_id}", msg.After.ID,
+	).Replace(template)
+}
@@ -0,0 +1,183 @@
+package sink
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"frigate-custom-reviews/internal/logger"
+	"frigate-custom-reviews/internal/models"
+)
+
+const defaultBufferSize = 100
+
+// Sink is an output that receives review lifecycle messages. Send is only
+// ever called from the sink's own dispatcher goroutine, so implementations
+// may block (e.g. while retrying) without affecting the engine or other sinks.
+type Sink interface {
+	Name() string
+	Send(msg models.MessagePayload) error
+	Close() error
+}
+
+// Dispatcher fans review messages out to every registered sink. Each sink
+// has its own filter, buffer and goroutine so errors and slow deliveries
+// stay isolated to that sink.
+type Dispatcher struct {
+	outputs []*output
+	wg      sync.WaitGroup
+
+	mu     sync.RWMutex // Guards closed against concurrent Publish / Close
+	closed bool
+}
+
+type output struct {
+	sink    Sink
+	options models.OutputOptions
+	queue   chan models.MessagePayload
+	dropped atomic.Int64
+}
+
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{}
+}
+
+// Add registers a sink and starts its delivery goroutine
+func (d *Dispatcher) Add(s Sink, opts models.OutputOptions) {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = defaultBufferSize
+	}
+
+	out := &output{
+		sink:    s,
+		options: opts,
+		queue:   make(chan models.MessagePayload, opts.BufferSize),
+	}
+	d.outputs = append(d.outputs, out)
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		out.run()
+	}()
+
+	logger.Infof("Enabled output: %s", s.Name())
+}
+
+// Len returns the number of registered sinks
+func (d *Dispatcher) Len() int {
+	return len(d.outputs)
+}
+
+// Publish queues msg on every sink whose filter accepts it. It never blocks;
+// a sink with a full buffer drops the message.
+func (d *Dispatcher) Publish(msg models.MessagePayload) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.closed {
+		return
+	}
+
+	for _, out := range d.outputs {
+		if !out.accepts(msg) {
+			continue
+		}
+
+		select {
+		case out.queue <- msg:
+		default:
+			dropped := out.dropped.Add(1)
+			logger.Warnf("Output %s buffer full, dropped '%s' message (%d dropped so far)",
+				out.sink.Name(), msg.Type, dropped)
+		}
+	}
+}
+
+// Close drains every sink's buffer and closes the sinks
+func (d *Dispatcher) Close() {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return
+	}
+	d.closed = true
+	for _, out := range d.outputs {
+		close(out.queue)
+	}
+	d.mu.Unlock()
+
+	d.wg.Wait()
+
+	for _, out := range d.outputs {
+		if err := out.sink.Close(); err != nil {
+			logger.Warnf("Error closing output %s: %v", out.sink.Name(), err)
+		}
+	}
+}
+
+func (o *output) run() {
+	for msg := range o.queue {
+		if err := o.sink.Send(msg); err != nil {
+			logger.Errorf("Output %s failed to send '%s' message: %v", o.sink.Name(), msg.Type, err)
+		}
+	}
+}
+
+func (o *output) accepts(msg models.MessagePayload) bool {
+	return accepts(o.options, msg)
+}
+
+// accepts applies the profile and type filters of opts to msg
+func accepts(opts models.OutputOptions, msg models.MessagePayload) bool {
+	if len(opts.Types) > 0 && !slices.Contains(opts.Types, msg.Type) {
+		return false
+	}
+
+	if len(opts.Profiles) > 0 {
+		if msg.After == nil || !slices.Contains(opts.Profiles, msg.After.ProfileName) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Filtered wraps s so it only receives the messages opts accepts, applying
+// an output's filters outside a Dispatcher (e.g. in a Fanout)
+func Filtered(s Sink, opts models.OutputOptions) Sink {
+	return &filtered{Sink: s, options: opts}
+}
+
+type filtered struct {
+	Sink
+	options models.OutputOptions
+}
+
+func (f *filtered) Send(msg models.MessagePayload) error {
+	if !accepts(f.options, msg) {
+		return nil
+	}
+	return f.Sink.Send(msg)
+}
+
+// Fanout delivers each message to its sinks synchronously and in order. It
+// suits offline tools (replay, backfill) where blocking is fine and dropping
+// messages on a full buffer is not.
+type Fanout []Sink
+
+func (f Fanout) Publish(msg models.MessagePayload) {
+	for _, s := range f {
+		if err := s.Send(msg); err != nil {
+			logger.Errorf("Output %s failed to send '%s' message: %v", s.Name(), msg.Type, err)
+		}
+	}
+}
+
+func (f Fanout) Close() {
+	for _, s := range f {
+		if err := s.Close(); err != nil {
+			logger.Warnf("Error closing output %s: %v", s.Name(), err)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package sink
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"frigate-custom-reviews/internal/models"
+)
+
+type published struct {
+	Topic    string
+	QoS      byte
+	Retained bool
+	Payload  interface{}
+}
+
+// MockMQTTPublisher captures messages for verification
+type MockMQTTPublisher struct {
+	Messages []published
+}
+
+func (m *MockMQTTPublisher) Publish(topic string, qos byte, retained bool, payload interface{}) error {
+	m.Messages = append(m.Messages, published{Topic: topic, QoS: qos, Retained: retained, Payload: payload})
+	return nil
+}
+
+func TestMQTT_TopicTemplates(t *testing.T) {
+	mockMQTT := &MockMQTTPublisher{}
+	s := NewMQTT(mockMQTT, models.MQTTConfig{
+		ReviewsPublishTopic: "reviews/{profile}",
+		StateTopic:          "reviews/{profile}/state",
+		QoS:                 models.MQTTQoSConfig{Reviews: 1, State: 2},
+	})
+
+	if err := s.Send(testMessage("new", "front")); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if len(mockMQTT.Messages) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(mockMQTT.Messages))
+	}
+
+	review := mockMQTT.Messages[0]
+	if review.Topic != "reviews/front" || review.QoS != 1 || review.Retained {
+		t.Errorf("Unexpected review publish: %+v", review)
+	}
+
+	state := mockMQTT.Messages[1]
+	if state.Topic != "reviews/front/state" || state.QoS != 2 || !state.Retained {
+		t.Errorf("Unexpected state publish: %+v", state)
+	}
+	if s, ok := state.Payload.(*models.ReviewState); !ok || s.State != "active" {
+		t.Errorf("Expected retained ReviewState, got %#v", state.Payload)
+	}
+
+	// Without a review state there is nothing to retain
+	mockMQTT.Messages = nil
+	if err := s.Send(models.MessagePayload{Type: "end"}); err != nil {
+		t.Fatalf("Send without After: %v", err)
+	}
+	if len(mockMQTT.Messages) != 1 || mockMQTT.Messages[0].Topic != "reviews/{profile}" {
+		t.Errorf("Expected only the review publish, got %+v", mockMQTT.Messages)
+	}
+}
+
+// blockingSink blocks every Send until released
+type blockingSink struct {
+	release chan struct{}
+	mu      sync.Mutex
+	sent    int
+}
+
+func (b *blockingSink) Name() string { return "blocking" }
+func (b *blockingSink) Close() error { return nil }
+func (b *blockingSink) Send(msg models.MessagePayload) error {
+	<-b.release
+	b.mu.Lock()
+	b.sent++
+	b.mu.Unlock()
+	return nil
+}
+
+// failingSink always errors
+type failingSink struct{}
+
+func (failingSink) Name() string                         { return "failing" }
+func (failingSink) Close() error                         { return nil }
+func (failingSink) Send(msg models.MessagePayload) error { return fmt.Errorf("boom") }
+
+// countingSink counts delivered messages
+type countingSink struct {
+	mu   sync.Mutex
+	sent int
+}
+
+func (c *countingSink) Name() string { return "counting" }
+func (c *countingSink) Close() error { return nil }
+func (c *countingSink) Send(msg models.MessagePayload) error {
+	c.mu.Lock()
+	c.sent++
+	c.mu.Unlock()
+	return nil
+}
+
+func TestDispatcher_IsolatesSinks(t *testing.T) {
+	slow := &blockingSink{release: make(chan struct{})}
+	healthy := &countingSink{}
+
+	d := NewDispatcher()
+	d.Add(slow, models.OutputOptions{BufferSize: 1})
+	d.Add(failingSink{}, models.OutputOptions{})
+	d.Add(healthy, models.OutputOptions{})
+
+	// Publish must never block, even though the slow sink's buffer overflows
+	for i := 0; i < 10; i++ {
+		d.Publish(testMessage("update", "front"))
+	}
+
+	close(slow.release)
+	d.Close()
+
+	if healthy.sent != 10 {
+		t.Errorf("Healthy sink received %d messages, want 10", healthy.sent)
+	}
+	if slow.sent >= 10 {
+		t.Errorf("Slow sink should have dropped messages, got %d", slow.sent)
+	}
+}
+
+func TestFiltered_AppliesOutputOptions(t *testing.T) {
+	counter := &countingSink{}
+	f := Fanout{Filtered(counter, models.OutputOptions{Profiles: []string{"front"}, Types: []string{"new", "end"}})}
+
+	f.Publish(testMessage("new", "front"))
+	f.Publish(testMessage("update", "front"))
+	f.Publish(testMessage("new", "back"))
+	f.Publish(testMessage("end", "front"))
+
+	if counter.sent != 2 {
+		t.Errorf("Expected 2 messages through the filter, got %d", counter.sent)
+	}
+	if name := Filtered(counter, models.OutputOptions{}).Name(); name != "counting" {
+		t.Errorf("Expected the wrapped sink's name, got %q", name)
+	}
+}
@@ -0,0 +1,178 @@
+package sink
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"frigate-custom-reviews/internal/logger"
+	"frigate-custom-reviews/internal/models"
+)
+
+const (
+	// SignatureHeader carries the hex HMAC-SHA256 of the body when a secret is set
+	SignatureHeader = "X-Signature-256"
+	// TypeHeader carries the review message type ("new", "update", "end")
+	TypeHeader = "X-Review-Type"
+)
+
+// Webhook delivers review messages to an HTTP endpoint
+type Webhook struct {
+	config     models.WebhookConfig
+	client     *http.Client
+	template   *template.Template
+	deadLetter *DeadLetterLog
+	backoff    time.Duration
+}
+
+func NewWebhook(cfg models.WebhookConfig) (*Webhook, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("webhook %q has no url", cfg.Name)
+	}
+	if cfg.Name == "" {
+		cfg.Name = cfg.URL
+	}
+	if cfg.Method == "" {
+		cfg.Method = http.MethodPost
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 10
+	}
+	if cfg.RetryBackoffMs == 0 {
+		cfg.RetryBackoffMs = 1000
+	}
+
+	w := &Webhook{
+		config:  cfg,
+		client:  &http.Client{Timeout: time.Duration(cfg.Timeout) * time.Second},
+		backoff: time.Duration(cfg.RetryBackoffMs) * time.Millisecond,
+	}
+
+	if cfg.Template != "" {
+		tmpl, err := template.New(cfg.Name).Funcs(templateFuncs).Parse(cfg.Template)
+		if err != nil {
+			return nil, fmt.Errorf("webhook %q: invalid template: %w", cfg.Name, err)
+		}
+		w.template = tmpl
+	}
+
+	if cfg.DeadLetterFile != "" {
+		w.deadLetter = NewDeadLetterLog(cfg.DeadLetterFile)
+	}
+
+	return w, nil
+}
+
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"join": strings.Join,
+}
+
+func (w *Webhook) Name() string {
+	return "webhook:" + w.config.Name
+}
+
+func (w *Webhook) Close() error {
+	return nil
+}
+
+// Send renders and delivers msg, retrying with exponential backoff. Messages
+// that still fail are written to the dead-letter log.
+func (w *Webhook) Send(msg models.MessagePayload) error {
+	body, err := w.render(msg)
+	if err != nil {
+		w.recordDeadLetter(msg, nil, 0, err)
+		return err
+	}
+
+	backoff := w.backoff
+	attempts := 0
+	for {
+		attempts++
+		err = w.send(msg.Type, body)
+		if err == nil {
+			logger.Debugf("Webhook %s delivered '%s' message", w.config.Name, msg.Type)
+			return nil
+		}
+
+		if attempts > w.config.MaxRetries {
+			break
+		}
+
+		logger.Warnf("Webhook %s attempt %d failed: %v. Retrying in %v", w.config.Name, attempts, err, backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+
+	w.recordDeadLetter(msg, body, attempts, err)
+	return fmt.Errorf("giving up after %d attempts: %w", attempts, err)
+}
+
+func (w *Webhook) render(msg models.MessagePayload) ([]byte, error) {
+	if w.template == nil {
+		data, err := json.Marshal(msg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal payload: %w", err)
+		}
+		return data, nil
+	}
+
+	var buf bytes.Buffer
+	if err := w.template.Execute(&buf, msg); err != nil {
+		return nil, fmt.Errorf("failed to render template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (w *Webhook) send(msgType string, body []byte) error {
+	req, err := http.NewRequest(w.config.Method, w.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TypeHeader, msgType)
+	for k, v := range w.config.Headers {
+		req.Header.Set(k, v)
+	}
+	if w.config.Secret != "" {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(w.config.Secret, body))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (w *Webhook) recordDeadLetter(msg models.MessagePayload, body []byte, attempts int, cause error) {
+	if w.deadLetter == nil {
+		return
+	}
+	if err := w.deadLetter.Write(w.config.Name, msg, body, attempts, cause); err != nil {
+		logger.Errorf("Webhook %s failed to write dead letter: %v", w.config.Name, err)
+	}
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of body using secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,190 @@
+package sink
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"frigate-custom-reviews/internal/models"
+)
+
+type capturedRequest struct {
+	Body      string
+	Signature string
+	Type      string
+}
+
+// fakeEndpoint records requests and fails the first `failures` of them
+func fakeEndpoint(t *testing.T, failures int) (*httptest.Server, func() []capturedRequest) {
+	var mu sync.Mutex
+	var requests []capturedRequest
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, capturedRequest{
+			Body:      string(body),
+			Signature: r.Header.Get(SignatureHeader),
+			Type:      r.Header.Get(TypeHeader),
+		})
+		if len(requests) <= failures {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, func() []capturedRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]capturedRequest(nil), requests...)
+	}
+}
+
+func testMessage(msgType, profile string) models.MessagePayload {
+	return models.MessagePayload{
+		Type: msgType,
+		After: &models.ReviewState{
+			ID:          "review1",
+			ProfileName: profile,
+			State:       "active",
+			Cameras:     []string{"cam1", "cam2"},
+		},
+	}
+}
+
+func TestWebhook_TemplateAndSignature(t *testing.T) {
+	srv, requests := fakeEndpoint(t, 0)
+
+	hook, err := NewWebhook(models.WebhookConfig{
+		URL:      srv.URL,
+		Template: `{"text": "{{.Type}} {{.After.ProfileName}} on {{join .After.Cameras ","}}"}`,
+		Secret:   "s3cret",
+	})
+	if err != nil {
+		t.Fatalf("NewWebhook: %v", err)
+	}
+	if err := hook.Send(testMessage("new", "front")); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	got := requests()
+	if len(got) != 1 {
+		t.Fatalf("Expected 1 request, got %d", len(got))
+	}
+
+	wantBody := `{"text": "new front on cam1,cam2"}`
+	if got[0].Body != wantBody {
+		t.Errorf("Body = %s, want %s", got[0].Body, wantBody)
+	}
+	if got[0].Type != "new" {
+		t.Errorf("Type header = %s, want new", got[0].Type)
+	}
+	if want := "sha256=" + Sign("s3cret", []byte(wantBody)); got[0].Signature != want {
+		t.Errorf("Signature = %s, want %s", got[0].Signature, want)
+	}
+}
+
+func TestDispatcher_FiltersWebhook(t *testing.T) {
+	srv, requests := fakeEndpoint(t, 0)
+
+	hook, err := NewWebhook(models.WebhookConfig{URL: srv.URL})
+	if err != nil {
+		t.Fatalf("NewWebhook: %v", err)
+	}
+
+	d := NewDispatcher()
+	d.Add(hook, models.OutputOptions{
+		Profiles: []string{"front"},
+		Types:    []string{"new", "end"},
+	})
+	d.Publish(testMessage("new", "front"))
+	d.Publish(testMessage("update", "front"))
+	d.Publish(testMessage("new", "back"))
+	d.Publish(testMessage("end", "front"))
+	d.Close()
+
+	got := requests()
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(got))
+	}
+	if got[0].Type != "new" || got[1].Type != "end" {
+		t.Errorf("Unexpected types delivered: %s, %s", got[0].Type, got[1].Type)
+	}
+
+	var payload models.MessagePayload
+	if err := json.Unmarshal([]byte(got[0].Body), &payload); err != nil {
+		t.Fatalf("Default body is not a MessagePayload: %v", err)
+	}
+	if payload.After.ProfileName != "front" {
+		t.Errorf("Expected profile front, got %s", payload.After.ProfileName)
+	}
+}
+
+func TestWebhook_RetriesThenSucceeds(t *testing.T) {
+	srv, requests := fakeEndpoint(t, 2)
+
+	hook, err := NewWebhook(models.WebhookConfig{
+		URL:            srv.URL,
+		MaxRetries:     3,
+		RetryBackoffMs: 1,
+	})
+	if err != nil {
+		t.Fatalf("NewWebhook: %v", err)
+	}
+	if err := hook.Send(testMessage("new", "front")); err != nil {
+		t.Fatalf("Expected delivery to succeed, got %v", err)
+	}
+	if got := len(requests()); got != 3 {
+		t.Errorf("Expected 3 attempts, got %d", got)
+	}
+}
+
+func TestWebhook_DeadLetter(t *testing.T) {
+	srv, requests := fakeEndpoint(t, 100)
+	deadLetterPath := filepath.Join(t.TempDir(), "dead.jsonl")
+
+	hook, err := NewWebhook(models.WebhookConfig{
+		Name:           "failing",
+		URL:            srv.URL,
+		MaxRetries:     2,
+		RetryBackoffMs: 1,
+		DeadLetterFile: deadLetterPath,
+	})
+	if err != nil {
+		t.Fatalf("NewWebhook: %v", err)
+	}
+	if err := hook.Send(testMessage("end", "front")); err == nil {
+		t.Fatal("Expected delivery to fail")
+	}
+	if got := len(requests()); got != 3 {
+		t.Errorf("Expected 3 attempts, got %d", got)
+	}
+
+	f, err := os.Open(deadLetterPath)
+	if err != nil {
+		t.Fatalf("Dead letter file not written: %v", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() {
+		t.Fatal("Dead letter file is empty")
+	}
+	var entry DeadLetter
+	if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+		t.Fatalf("Invalid dead letter entry: %v", err)
+	}
+	if entry.Webhook != "failing" || entry.Attempts != 3 || entry.Message.Type != "end" {
+		t.Errorf("Unexpected dead letter entry: %+v", entry)
+	}
+}
@@ -0,0 +1,31 @@
+package tlsconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"frigate-custom-reviews/internal/models"
+)
+
+func TestBuild(t *testing.T) {
+	if cfg, err := Build(models.TLSConfig{}); cfg != nil || err != nil {
+		t.Errorf("Expected no TLS config when nothing is set, got %v, %v", cfg, err)
+	}
+
+	dir := t.TempDir()
+	bogus := filepath.Join(dir, "bogus.pem")
+	os.WriteFile(bogus, []byte("not a certificate"), 0o600)
+
+	bad := []models.TLSConfig{
+		{CAFile: filepath.Join(dir, "missing.pem")},
+		{CAFile: bogus},
+		{CertFile: bogus},
+		{CertFile: bogus, KeyFile: bogus},
+	}
+	for _, cfg := range bad {
+		if _, err := Build(cfg); err == nil {
+			t.Errorf("Expected error for %+v", cfg)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package whatif
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"frigate-custom-reviews/internal/models"
+)
+
+// Difference kinds
+const (
+	KindAdded     = "added"     // Review only produced by config B
+	KindRemoved   = "removed"   // Review only produced by config A
+	KindMerged    = "merged"    // Several A reviews became fewer B reviews
+	KindSplit     = "split"     // An A review became several B reviews
+	KindChanged   = "changed"   // One-to-one, but duration or events differ
+	KindUnchanged = "unchanged" // One-to-one and identical
+)
+
+// ReviewSummary is the part of a review relevant for comparison
+type ReviewSummary struct {
+	ID        string   `json:"id"`
+	StartTime float64  `json:"start_time"`
+	EndTime   float64  `json:"end_time"`
+	Duration  float64  `json:"duration"`
+	Events    []string `json:"events"`
+}
+
+// Difference links the reviews from A and B that share events
+type Difference struct {
+	Kind string          `json:"kind"`
+	A    []ReviewSummary `json:"a"`
+	B    []ReviewSummary `json:"b"`
+}
+
+type ProfileReport struct {
+	Profile     string       `json:"profile"`
+	CountA      int          `json:"count_a"`
+	CountB      int          `json:"count_b"`
+	TotalDurA   float64      `json:"total_duration_a"`
+	TotalDurB   float64      `json:"total_duration_b"`
+	Counts      Counts       `json:"counts"`
+	Differences []Difference `json:"differences"`
+}
+
+type Counts struct {
+	Added     int `json:"added"`
+	Removed   int `json:"removed"`
+	Merged    int `json:"merged"`
+	Split     int `json:"split"`
+	Changed   int `json:"changed"`
+	Unchanged int `json:"unchanged"`
+}
+
+type Report struct {
+	Profiles []ProfileReport `json:"profiles"`
+}
+
+// Compare matches the reviews produced by two configs on the same events.
+// Reviews are linked when they share at least one Frigate event; each
+// connected group is then classified as added, removed, merged, split,
+// changed or unchanged. Unchanged groups are counted but not listed.
+func Compare(a, b []models.ReviewState) Report {
+	byProfileA := groupByProfile(a)
+	byProfileB := groupByProfile(b)
+
+	var names []string
+	for name := range byProfileA {
+		names = append(names, name)
+	}
+	for name := range byProfileB {
+		if _, ok := byProfileA[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var report Report
+	for _, name := range names {
+		report.Profiles = append(report.Profiles, compareProfile(name, byProfileA[name], byProfileB[name]))
+	}
+	return report
+}
+
+func groupByProfile(reviews []models.ReviewState) map[string][]ReviewSummary {
+	out := make(map[string][]ReviewSummary)
+	for _, r := range reviews {
+		out[r.ProfileName] = append(out[r.ProfileName], summarize(r))
+	}
+	for _, list := range out {
+		sort.Slice(list, func(i, j int) bool { return list[i].StartTime < list[j].StartTime })
+	}
+	return out
+}
+
+func summarize(r models.ReviewState) ReviewSummary {
+	s := ReviewSummary{ID: r.ID, StartTime: r.StartTime, EndTime: r.StartTime}
+	if r.EndTime != nil {
+		s.EndTime = *r.EndTime
+	}
+	s.Duration = s.EndTime - s.StartTime
+	for _, e := range r.LinkedEvents {
+		s.Events = append(s.Events, e.ID)
+	}
+	sort.Strings(s.Events)
+	return s
+}
+
+func compareProfile(name string, a, b []ReviewSummary) ProfileReport {
+	pr := ProfileReport{Profile: name, CountA: len(a), CountB: len(b)}
+	for _, r := range a {
+		pr.TotalDurA += r.Duration
+	}
+	for _, r := range b {
+		pr.TotalDurB += r.Duration
+	}
+
+	// Union-find over A (0..len(a)-1) and B (len(a)..) joined by shared events
+	parent := make([]int, len(a)+len(b))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	owner := make(map[string]int)
+	for i, r := range a {
+		for _, id := range r.Events {
+			owner[id] = i
+		}
+	}
+	for j, r := range b {
+		for _, id := range r.Events {
+			if i, ok := owner[id]; ok {
+				parent[find(len(a)+j)] = find(i)
+			}
+		}
+	}
+
+	groups := make(map[int]*Difference)
+	var roots []int
+	for i := range parent {
+		root := find(i)
+		d, ok := groups[root]
+		if !ok {
+			d = &Difference{}
+			groups[root] = d
+			roots = append(roots, root)
+		}
+		if i < len(a) {
+			d.A = append(d.A, a[i])
+		} else {
+			d.B = append(d.B, b[i-len(a)])
+		}
+	}
+
+	for _, root := range roots {
+		d := groups[root]
+		d.Kind = classify(d.A, d.B)
+		switch d.Kind {
+		case KindAdded:
+			pr.Counts.Added++
+		case KindRemoved:
+			pr.Counts.Removed++
+		case KindMerged:
+			pr.Counts.Merged++
+		case KindSplit:
+			pr.Counts.Split++
+		case KindChanged:
+			pr.Counts.Changed++
+		case KindUnchanged:
+			pr.Counts.Unchanged++
+			continue
+		}
+		pr.Differences = append(pr.Differences, *d)
+	}
+
+	sort.SliceStable(pr.Differences, func(i, j int) bool {
+		return firstStart(pr.Differences[i]) < firstStart(pr.Differences[j])
+	})
+
+	return pr
+}
+
+func classify(a, b []ReviewSummary) string {
+	switch {
+	case len(a) == 0:
+		return KindAdded
+	case len(b) == 0:
+		return KindRemoved
+	case len(a) > len(b):
+		return KindMerged
+	case len(a) < len(b):
+		return KindSplit
+	}
+
+	// Groups with equal counts on both sides are compared pairwise in start order
+	for i := range a {
+		if a[i].StartTime != b[i].StartTime || a[i].EndTime != b[i].EndTime || !slices.Equal(a[i].Events, b[i].Events) {
+			return KindChanged
+		}
+	}
+	return KindUnchanged
+}
+
+func firstStart(d Difference) float64 {
+	if len(d.A) > 0 {
+		return d.A[0].StartTime
+	}
+	return d.B[0].StartTime
+}
+
+// WriteText prints a human-readable summary of the report
+func (r Report) WriteText(w io.Writer) {
+	for _, p := range r.Profiles {
+		fmt.Fprintf(w, "Profile %s\n", p.Profile)
+		fmt.Fprintf(w, "  reviews:  %d -> %d (%+d)\n", p.CountA, p.CountB, p.CountB-p.CountA)
+		fmt.Fprintf(w, "  duration: %s -> %s total, %s -> %s average\n",
+			seconds(p.TotalDurA), seconds(p.TotalDurB), seconds(average(p.TotalDurA, p.CountA)), seconds(average(p.TotalDurB, p.CountB)))
+		fmt.Fprintf(w, "  added %d, removed %d, merged %d, split %d, changed %d, unchanged %d\n",
+			p.Counts.Added, p.Counts.Removed, p.Counts.Merged, p.Counts.Split, p.Counts.Changed, p.Counts.Unchanged)
+
+		for _, d := range p.Differences {
+			fmt.Fprintf(w, "    %-8s %s => %s\n", d.Kind, describe(d.A), describe(d.B))
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+func describe(reviews []ReviewSummary) string {
+	if len(reviews) == 0 {
+		return "-"
+	}
+	parts := make([]string, 0, len(reviews))
+	for _, r := range reviews {
+		start := time.Unix(int64(r.StartTime), 0).Local().Format("2006-01-02 15:04:05")
+		parts = append(parts, fmt.Sprintf("[%s %s, %d events]", start, seconds(r.Duration), len(r.Events)))
+	}
+	return strings.Join(parts, " ")
+}
+
+func average(total float64, count int) float64 {
+	if count == 0 {
+		return 0
+	}
+	return total / float64(count)
+}
+
+func seconds(s float64) string {
+	return (time.Duration(s) * time.Second).String()
+}
@@ -0,0 +1,73 @@
+package whatif
+
+import (
+	"testing"
+
+	"frigate-custom-reviews/internal/models"
+)
+
+func review(id, profile string, start, end float64, events ...string) models.ReviewState {
+	r := models.ReviewState{ID: id, ProfileName: profile, StartTime: start, EndTime: &end}
+	for _, e := range events {
+		r.LinkedEvents = append(r.LinkedEvents, models.LinkedEventSummary{ID: e})
+	}
+	return r
+}
+
+func TestCompare(t *testing.T) {
+	a := []models.ReviewState{
+		review("a1", "front", 100, 110, "e1"),
+		review("a2", "front", 120, 130, "e2"),       // merged with a1 in B
+		review("a3", "front", 200, 260, "e3", "e4"), // split in B
+		review("a4", "front", 300, 310, "e5"),       // unchanged
+		review("a5", "front", 400, 410, "e6"),       // removed
+		review("a6", "front", 500, 510, "e7"),       // changed (longer)
+		review("a7", "back", 100, 110, "e8"),        // profile dropped from B
+	}
+	b := []models.ReviewState{
+		review("b1", "front", 100, 130, "e1", "e2"),
+		review("b2", "front", 200, 210, "e3"),
+		review("b3", "front", 250, 260, "e4"),
+		review("b4", "front", 300, 310, "e5"),
+		review("b5", "front", 500, 530, "e7"),
+		review("b6", "front", 600, 610, "e9"), // added
+	}
+
+	report := Compare(a, b)
+	if len(report.Profiles) != 2 {
+		t.Fatalf("Expected 2 profiles, got %d", len(report.Profiles))
+	}
+
+	back := report.Profiles[0]
+	if back.Profile != "back" || back.Counts.Removed != 1 || back.CountB != 0 {
+		t.Errorf("Unexpected report for back: %+v", back)
+	}
+
+	front := report.Profiles[1]
+	want := Counts{Added: 1, Removed: 1, Merged: 1, Split: 1, Changed: 1, Unchanged: 1}
+	if front.Counts != want {
+		t.Errorf("Counts = %+v, want %+v", front.Counts, want)
+	}
+	if front.CountA != 6 || front.CountB != 6 {
+		t.Errorf("Review counts = %d -> %d, want 6 -> 6", front.CountA, front.CountB)
+	}
+	if front.TotalDurA != 110 || front.TotalDurB != 100 {
+		t.Errorf("Durations = %v -> %v, want 110 -> 100", front.TotalDurA, front.TotalDurB)
+	}
+
+	// Differences are listed in time order and exclude unchanged groups
+	var kinds []string
+	for _, d := range front.Differences {
+		kinds = append(kinds, d.Kind)
+	}
+	wantKinds := []string{KindMerged, KindSplit, KindRemoved, KindChanged, KindAdded}
+	if len(kinds) != len(wantKinds) {
+		t.Fatalf("Differences = %v, want %v", kinds, wantKinds)
+	}
+	for i := range kinds {
+		if kinds[i] != wantKinds[i] {
+			t.Errorf("Differences = %v, want %v", kinds, wantKinds)
+			break
+		}
+	}
+}